@@ -0,0 +1,189 @@
+package sheet
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Option MarshalやUnmarshalの挙動を変更する
+type Option func(*config)
+
+type config struct {
+	// tolerant 数値の桁区切り(1,234)、先頭の+、整数への1.0を許容するか否か
+	tolerant bool
+	// truthy, falsy 真偽値として扱う文字列、encode時は先頭の文字列を使う
+	truthy []string
+	falsy  []string
+	// validation ValidationWriterにenumの入力規則を設定するか否か
+	validation bool
+	null       NullPolicy
+	// maxDepth 再帰的な型を展開する深さ、0の場合は再帰的な型をエラーにする
+	maxDepth int
+	// flat ヘッダーを1行のパスで表すか否か
+	flat bool
+	// transpose 行と列を入れ替えるか否か
+	transpose bool
+	// headerCell, detectHeader UnmarshalSheetでヘッダーを探す方法
+	headerCell   string
+	detectHeader bool
+	// comment コメントの行の先頭の文字列
+	comment string
+	// commentColumns コメントの列のヘッダーの先頭の文字列
+	commentColumns []string
+	// columnOrder 先頭に並べるフィールドのキーの順番
+	columnOrder map[string]int
+	// columnOrderKey columnOrderを表す文字列、フィールドの順番のキャッシュのキー
+	columnOrderKey string
+	// titleMatch decode時にヘッダーをtitle=とも照合するか否か
+	titleMatch bool
+}
+
+// Blank 値が無い場合のセルの表現
+type Blank int
+
+const (
+	// BlankCell 空のセル
+	BlankCell Blank = iota
+	// BlankLiteral NullPolicy.Literalの文字列
+	BlankLiteral
+	// BlankZero 型のゼロ値
+	BlankZero
+)
+
+// NullPolicy ゼロ値、nilのポインタ、空のスライスをどう表現するか
+// decode時はLiteralと一致するセルを空のセルとして扱う
+type NullPolicy struct {
+	Zero  Blank
+	Nil   Blank
+	Empty Blank
+	// Literal BlankLiteralの文字列、空の場合はNULL
+	Literal string
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{
+		null: NullPolicy{
+			Zero:  BlankZero,
+			Nil:   BlankCell,
+			Empty: BlankCell,
+		},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// WithTolerantNumbers スプレッドシートが出力しがちな数値の表記を許容する
+func WithTolerantNumbers() Option {
+	return func(c *config) {
+		c.tolerant = true
+	}
+}
+
+// WithBoolVocabulary 真偽値の表記を指定する、decode時はこれ以外の値をエラーにする
+func WithBoolVocabulary(truthy, falsy []string) Option {
+	return func(c *config) {
+		c.truthy = truthy
+		c.falsy = falsy
+	}
+}
+
+// parseBool tagのbool=、WithBoolVocabulary、strconv.ParseBoolの順に解釈する
+func (c *config) parseBool(value string, opt *option) (bool, error) {
+	truthy, falsy := c.truthy, c.falsy
+	if opt != nil && opt.boolTrue != "" {
+		truthy, falsy = []string{opt.boolTrue}, []string{opt.boolFalse}
+	}
+	if len(truthy) == 0 && len(falsy) == 0 {
+		return strconv.ParseBool(value)
+	}
+	for _, x := range truthy {
+		if strings.EqualFold(value, x) {
+			return true, nil
+		}
+	}
+	for _, x := range falsy {
+		if strings.EqualFold(value, x) {
+			return false, nil
+		}
+	}
+	return false, fmt.Errorf("unrecognised bool value %q", value)
+}
+
+// formatBool 表記の指定が無ければboolのまま返す
+func (c *config) formatBool(b bool, opt *option) interface{} {
+	truthy, falsy := c.truthy, c.falsy
+	if opt != nil && opt.boolTrue != "" {
+		truthy, falsy = []string{opt.boolTrue}, []string{opt.boolFalse}
+	}
+	if b && len(truthy) > 0 {
+		return truthy[0]
+	}
+	if !b && len(falsy) > 0 {
+		return falsy[0]
+	}
+	return b
+}
+
+// WithDataValidation ValidationWriterに対応した書き出し先にenumのラベルを入力規則として設定する
+func WithDataValidation() Option {
+	return func(c *config) {
+		c.validation = true
+	}
+}
+
+// WithNullPolicy ゼロ値、nilのポインタ、空のスライスの表現を指定する
+func WithNullPolicy(policy NullPolicy) Option {
+	return func(c *config) {
+		c.null = policy
+	}
+}
+
+// nullLiteral BlankLiteralを使わない場合は空文字
+func (c *config) nullLiteral() string {
+	p := c.null
+	if p.Zero != BlankLiteral && p.Nil != BlankLiteral && p.Empty != BlankLiteral {
+		return ""
+	}
+	if p.Literal == "" {
+		return "NULL"
+	}
+	return p.Literal
+}
+
+// WithMaxDepth 再帰的な型をn段まで展開する、それより深い値はnilのみ許容する
+func WithMaxDepth(n int) Option {
+	return func(c *config) {
+		c.maxDepth = n
+	}
+}
+
+// WithTranspose 行と列を入れ替え、キーを縦に並べたシートとして扱う
+// 1レコードずつ書き出すStreamEncoder, StreamDecoderでは使えない
+func WithTranspose() Option {
+	return func(c *config) {
+		c.transpose = true
+	}
+}
+
+// WithColumnOrder キーの順番にフィールドを並べる、tagのorder=より優先する
+// 入れ子の構造体のフィールドにも適用し、含まれないフィールドは後ろに並べる
+func WithColumnOrder(keys ...string) Option {
+	return func(c *config) {
+		c.columnOrder = make(map[string]int, len(keys))
+		for i, key := range keys {
+			c.columnOrder[key] = i
+		}
+		c.columnOrderKey = strings.Join(keys, "\x00")
+	}
+}
+
+// WithTitleMatch decode時にヘッダーをキーに加えてtitle=, alias=の値とも照合する
+// 照合では大文字小文字と空白を区別しない
+func WithTitleMatch() Option {
+	return func(c *config) {
+		c.titleMatch = true
+	}
+}
@@ -3,6 +3,7 @@ package sheet
 import (
 	"errors"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -42,19 +43,71 @@ func (r *rows) truncate() {
 }
 
 type decoder struct {
+	*config
 	formats [][]string
 	values  [][]string
-	index   map[string]map[string]string
+	// typed 型付きの値、Decodeでは持たない
+	typed [][]interface{}
+	// index 型ごとのヘッダーのキーからフィールド名
+	index map[reflect.Type]map[string]string
+	// titles title=, alias=とキーを正規化したものからフィールド名、WithTitleMatchの場合のみ持つ
+	titles map[reflect.Type]map[string]string
+	// columns 列ごとの値が入っている行、Decodeごとに1度だけ走査する
+	columns map[int][]int
+	// end 現在decodeしているレコードの終端の行
+	end int
+	// literal 空のセルとして扱うNULLの文字列
+	literal string
+	// ignored コメントの列
+	ignored map[int]bool
 }
 
-func newDecoder(formats [][]string) *decoder {
+func newDecoder(formats [][]string, opts ...Option) *decoder {
 	dec := &decoder{
-		index: map[string]map[string]string{},
+		config:  newConfig(opts),
+		index:   map[reflect.Type]map[string]string{},
+		titles:  map[reflect.Type]map[string]string{},
+		columns: map[int][]int{},
+	}
+	dec.literal = dec.nullLiteral()
+	if dec.transpose {
+		formats = transpose(formats)
+	}
+	if len(dec.commentColumns) > 0 {
+		formats = dec.ignoreColumns(formats)
+	}
+	if dec.flat && len(formats) > 0 {
+		formats = stackHeader(formats[0])
 	}
 	dec.setFormat(formats)
 	return dec
 }
 
+// ignoreColumns コメントの列を記録し、その列のヘッダーを空にしたコピーを返す
+func (dec *decoder) ignoreColumns(formats [][]string) [][]string {
+	dec.ignored = map[int]bool{}
+	for i := range formats {
+		for j := range formats[i] {
+			if dec.isCommentColumn(formats[i][j]) {
+				dec.ignored[j] = true
+			}
+		}
+	}
+	if len(dec.ignored) == 0 {
+		return formats
+	}
+	ret := make([][]string, len(formats))
+	for i := range formats {
+		ret[i] = make([]string, len(formats[i]))
+		for j := range formats[i] {
+			if !dec.ignored[j] {
+				ret[i][j] = formats[i][j]
+			}
+		}
+	}
+	return ret
+}
+
 func (dec *decoder) setFormat(formats [][]string) {
 	maxColumn := 0
 	for i := range formats {
@@ -74,8 +127,43 @@ func (dec *decoder) setFormat(formats [][]string) {
 	dec.formats = ret
 }
 
+// DecodeValues 型付きの値からdecodeする、数値や時刻は文字列を経由せずに代入する
+func (dec *decoder) DecodeValues(values [][]interface{}, v interface{}) error {
+	strs := make([][]string, len(values))
+	for i := range values {
+		strs[i] = make([]string, len(values[i]))
+		for j := range values[i] {
+			strs[i][j] = formatValue(values[i][j])
+		}
+	}
+	err := dec.decodeValues(strs, v, values)
+	dec.typed = nil
+	return err
+}
+
+func (dec *decoder) DecodeGrid(grid *Grid, v interface{}) error {
+	return dec.DecodeValues(grid.Values(), v)
+}
+
 func (dec *decoder) Decode(values [][]string, v interface{}) error {
+	return dec.decodeValues(values, v, nil)
+}
+
+func (dec *decoder) decodeValues(values [][]string, v interface{}, typed [][]interface{}) error {
+	if dec.transpose {
+		values = transpose(values)
+		typed = transpose(typed)
+	}
+	var rows []int
+	if dec.comment != "" {
+		values, typed, rows = dec.skipComments(values, typed)
+	}
 	dec.values = values
+	dec.typed = typed
+	dec.end = len(values)
+	for column := range dec.columns {
+		delete(dec.columns, column)
+	}
 
 	rv := reflect.ValueOf(v)
 	if rv.Kind() != reflect.Ptr || rv.IsNil() {
@@ -83,172 +171,202 @@ func (dec *decoder) Decode(values [][]string, v interface{}) error {
 	}
 
 	rv = rv.Elem()
-	dec.createIndex(rv)
-	name := rv.Type().String()
-	row := 0
-	for column := range dec.formats[row] {
-		key := dec.formats[row][column]
-		if key == "" {
+	if err := dec.createIndex(rv); err != nil {
+		return err
+	}
+	if len(dec.formats) == 0 {
+		return nil
+	}
+	// 最上位の構造体は0行目のヘッダーをすべて使う
+	return dec.cellError(dec.decodeStruct(rv, -1, 0, 0, len(dec.formats[0])), rows)
+}
+
+// skipComments コメントの行を取り除き、残した行の元の行番号を返す
+func (dec *decoder) skipComments(values [][]string, typed [][]interface{}) ([][]string, [][]interface{}, []int) {
+	rows := make([][]string, 0, len(values))
+	index := make([]int, 0, len(values))
+	var typedRows [][]interface{}
+	for i := range values {
+		if dec.isComment(values[i]) {
 			continue
 		}
-		keyIdx := strings.Index(key, ":")
-		var opt *option
-		if keyIdx > 0 && keyIdx+1 < len(key) {
-			// option
-			opt = newOption(key[keyIdx+1:], false)
-		}
-		if keyIdx > 0 {
-			key = key[:keyIdx]
-		}
-		key = dec.getIndex(name, key)
-		field, ok := rv.Type().FieldByName(key)
-		if ok && field.Tag.Get(tagName) != "-" {
-			value := rv.FieldByName(key)
-			if value.IsValid() {
-				if err := dec.decode(value, row, column, opt); err != nil {
-					return err
-				}
-			}
+		rows = append(rows, values[i])
+		index = append(index, i)
+		if i < len(typed) {
+			typedRows = append(typedRows, typed[i])
 		}
-		resetOption(opt)
 	}
-	return nil
+	return rows, typedRows, index
 }
 
-func (dec *decoder) getIndex(name, key string) string {
-	if _, ok := dec.index[name]; !ok {
-		return key
+// cellError CellErrorの位置をコメントの行を除く前、行と列を入れ替える前の値の位置にする
+func (dec *decoder) cellError(err error, rows []int) error {
+	var e *CellError
+	if !errors.As(err, &e) {
+		return err
+	}
+	if rows != nil && e.Row < len(rows) {
+		e.Row = rows[e.Row]
 	}
-	k, ok := dec.index[name][key]
-	if !ok {
-		return key
+	if dec.transpose {
+		e.Row, e.Column = e.Column, e.Row
 	}
-	return k
+	return err
 }
 
-func (dec *decoder) createIndex(v reflect.Value) {
-	name := v.Type().String()
-	if _, ok := dec.index[name]; !ok {
-		dec.index[name] = map[string]string{}
+func (dec *decoder) getIndex(t reflect.Type, key string) string {
+	if k, ok := dec.index[t][key]; ok {
+		return k
 	}
+	if k, ok := dec.titles[t][normalizeTitle(key)]; ok && dec.titleMatch {
+		return k
+	}
+	return key
+}
+
+// normalizeTitle 大文字小文字と空白を区別しないように正規化する
+func normalizeTitle(title string) string {
+	return strings.ToLower(strings.Join(strings.Fields(title), ""))
+}
+
+func (dec *decoder) createIndex(v reflect.Value) error {
+	t := v.Type()
+	if _, ok := dec.index[t]; ok {
+		// 作成済み、または再帰的な型で作成中
+		return nil
+	}
+	dec.index[t] = map[string]string{}
+	dec.titles[t] = map[string]string{}
 
 	for i := 0; i < v.Type().NumField(); i++ {
 		field := v.Type().Field(i)
 		switch field.Type.Kind() {
 		case reflect.Ptr:
 			elem := reflect.New(v.Field(i).Type().Elem()).Elem()
-			if elem.Kind() == reflect.Struct {
-				dec.createIndex(elem)
+			if layoutKind(elem.Type()) == reflect.Struct {
+				if err := dec.createIndex(elem); err != nil {
+					return err
+				}
 			}
 		case reflect.Struct:
-			if v.Field(i).Type() != typeOfTime {
-				dec.createIndex(v.Field(i))
+			if !isLeaf(v.Field(i).Type()) {
+				if err := dec.createIndex(v.Field(i)); err != nil {
+					return err
+				}
+			}
+		case reflect.Interface:
+			if u := lookupUnion(field.Type); u != nil {
+				for _, t := range u.types {
+					if t.Kind() == reflect.Ptr {
+						t = t.Elem()
+					}
+					if err := dec.createIndex(reflect.New(t).Elem()); err != nil {
+						return err
+					}
+				}
 			}
 		case reflect.Array:
 			if v.Field(i).Type().Elem().Kind() == reflect.Ptr {
 				elem := reflect.New(v.Field(i).Type().Elem().Elem()).Elem()
 				if elem.Kind() == reflect.Struct {
-					dec.createIndex(elem)
+					if err := dec.createIndex(elem); err != nil {
+						return err
+					}
 				}
 			}
 			if v.Field(i).Type().Elem().Kind() == reflect.Struct {
 				elem := reflect.New(v.Field(i).Type().Elem()).Elem()
-				dec.createIndex(elem)
+				if err := dec.createIndex(elem); err != nil {
+					return err
+				}
 			}
 		case reflect.Slice:
 			if v.Field(i).Type().Elem().Kind() == reflect.Ptr {
 				elem := reflect.New(v.Field(i).Type().Elem().Elem()).Elem()
 				if elem.Kind() == reflect.Struct {
-					dec.createIndex(elem)
+					if err := dec.createIndex(elem); err != nil {
+						return err
+					}
 				}
 			}
 			if v.Field(i).Type().Elem().Kind() == reflect.Struct {
 				elem := reflect.New(v.Field(i).Type().Elem()).Elem()
-				dec.createIndex(elem)
+				if err := dec.createIndex(elem); err != nil {
+					return err
+				}
 			}
 		}
-		key := field.Name
-		tags := strings.Split(field.Tag.Get(tagName), ",")
-		if len(tags) > 0 {
-			key = tags[0]
+		key, opt, err := fieldTag(field)
+		if err != nil {
+			return err
+		}
+		dec.index[t][key] = field.Name
+		for _, alias := range opt.aliases {
+			dec.index[t][alias] = field.Name
+		}
+		if dec.titleMatch {
+			for _, title := range append([]string{key, opt.title}, opt.aliases...) {
+				if title != "" {
+					dec.titles[t][normalizeTitle(title)] = field.Name
+				}
+			}
 		}
-		dec.index[name][key] = field.Name
+		resetOption(opt)
 	}
+	return nil
 }
 
-func (dec *decoder) decode(v reflect.Value, row, column int, opt *option) error {
-	switch v.Kind() {
+// decode level行目のヘッダーのcolumn列目からlimit列目の手前までを、row行目を先頭にdecodeする
+func (dec *decoder) decode(v reflect.Value, level, row, column, limit int, opt *option) error {
+	if opt != nil && opt.isHorizontal {
+		switch v.Kind() {
+		case reflect.Array, reflect.Slice:
+			return dec.decodeHorizontal(v, level, row, column, limit, opt)
+		}
+	}
+	switch layoutKind(v.Type()) {
 	case reflect.Ptr:
 		elem := reflect.New(v.Type().Elem())
-		switch elem.Elem().Kind() {
+		switch layoutKind(elem.Elem().Type()) {
 		case reflect.Struct:
-			isExist := false
-			for i, format := range dec.formats[row+1][column:] {
-				if format == "" {
-					break
-				}
-				if x := dec.getValue(row, column+i); x != "" {
-					isExist = true
-					break
-				}
-			}
-			if isExist {
-				if err := dec.decode(elem.Elem(), row, column, opt); err != nil {
+			if dec.exists(level, row, column, limit) {
+				if err := dec.decode(elem.Elem(), level, row, column, limit, opt); err != nil {
 					return err
 				}
 				v.Set(elem)
 			}
 		default:
 			if x := dec.getValue(row, column); x != "" {
-				if err := dec.decode(elem.Elem(), row, column, opt); err != nil {
+				if err := dec.decode(elem.Elem(), level, row, column, limit, opt); err != nil {
 					return err
 				}
 				v.Set(elem)
 			}
 		}
 	case reflect.Struct:
-		switch v.Type() {
-		case typeOfTime:
-			x := dec.getValue(row, column)
-			t, err := decodeDatetime(x, opt)
-			if err != nil {
-				return err
-			}
-			v.Set(reflect.ValueOf(t))
-		default:
-			if err := dec.decodeStruct(v, row, column, 0); err != nil {
-				return err
-			}
+		if err := dec.decodeStruct(v, level, row, column, limit); err != nil {
+			return err
+		}
+	case reflect.Interface:
+		if err := dec.decodeInterface(v, level, row, column, limit); err != nil {
+			return err
 		}
 	case reflect.Array:
-		switch v.Index(0).Kind() {
+		switch layoutKind(v.Type().Elem()) {
 		case reflect.Ptr:
-			pType := reflect.New(v.Index(0).Type().Elem())
-			switch pType.Elem().Kind() {
+			pType := reflect.New(v.Type().Elem().Elem())
+			switch layoutKind(pType.Elem().Type()) {
 			case reflect.Struct:
 				rows := dec.targetRows(row, column)
-				for idx, i := range rows.list {
-					if idx >= v.Len() {
+				for k, i := range rows.list {
+					if i >= v.Len() {
 						break
 					}
-					isExist := false
-					for j, format := range dec.formats[row+1][column:] {
-						if format == "" {
-							break
-						}
-						if format == "_index" {
-							continue
-						}
-						if x := dec.getValue(row+i, column+j); x != "" {
-							isExist = true
-							break
-						}
-					}
-					if !isExist {
+					if !dec.exists(level, row+i, column, limit) {
 						continue
 					}
 					elem := reflect.New(pType.Type().Elem())
-					if err := dec.decodeStruct(elem.Elem(), row, column, i); err != nil {
+					if err := dec.decodeElem(elem.Elem(), level, row, column, limit, rows, k); err != nil {
 						return err
 					}
 					v.Index(i).Set(elem)
@@ -256,12 +374,11 @@ func (dec *decoder) decode(v reflect.Value, row, column int, opt *option) error
 				resetRowsPool(rows)
 			default:
 				for i := 0; i < v.Len(); i++ {
-					x := dec.getValue(row+i, column)
-					if x == "" {
+					if x := dec.getValue(row+i, column); x == "" {
 						continue
 					}
 					elem := reflect.New(v.Index(i).Type().Elem())
-					if err := dec.set(elem.Elem(), x, opt); err != nil {
+					if err := dec.setAt(elem.Elem(), row+i, column, opt); err != nil {
 						return err
 					}
 					v.Index(i).Set(elem)
@@ -269,44 +386,33 @@ func (dec *decoder) decode(v reflect.Value, row, column int, opt *option) error
 			}
 		case reflect.Struct:
 			rows := dec.targetRows(row, column)
-			for _, i := range rows.list {
-				if err := dec.decodeStruct(v.Index(i), row, column, i); err != nil {
+			for k, i := range rows.list {
+				if i >= v.Len() {
+					break
+				}
+				if err := dec.decodeElem(v.Index(i), level, row, column, limit, rows, k); err != nil {
 					return err
 				}
 			}
 			resetRowsPool(rows)
 		default:
 			for i := 0; i < v.Len(); i++ {
-				x := dec.getValue(row+i, column)
-				if err := dec.set(v.Index(i), x, opt); err != nil {
+				if err := dec.setAt(v.Index(i), row+i, column, opt); err != nil {
 					return err
 				}
 			}
 		}
 	case reflect.Slice:
 		elems := reflect.MakeSlice(v.Type(), 0, 1) // 最終的に蓄積するスライス
-		switch v.Type().Elem().Kind() {
+		switch layoutKind(v.Type().Elem()) {
 		case reflect.Ptr:
-			switch v.Type().Elem().Elem().Kind() {
+			switch layoutKind(v.Type().Elem().Elem()) {
 			case reflect.Struct:
 				rows := dec.targetRows(row, column)
-				for _, i := range rows.list {
-					isExist := false
-					for j, format := range dec.formats[row+1][column:] {
-						if format == "" {
-							break
-						}
-						if format == "_index" {
-							continue
-						}
-						if x := dec.getValue(row+i, column+j); x != "" {
-							isExist = true
-							break
-						}
-					}
-					if isExist {
+				for k, i := range rows.list {
+					if dec.exists(level, row+i, column, limit) {
 						elem := reflect.New(v.Type().Elem().Elem())
-						if err := dec.decodeStruct(elem.Elem(), row, column, i); err != nil {
+						if err := dec.decodeElem(elem.Elem(), level, row, column, limit, rows, k); err != nil {
 							return err
 						}
 						elems = reflect.Append(elems, elem)
@@ -321,7 +427,7 @@ func (dec *decoder) decode(v reflect.Value, row, column int, opt *option) error
 					for _, x := range strings.Split(dec.getValue(row, column), ",") {
 						elem := reflect.New(v.Type().Elem().Elem())
 						if err := dec.set(elem.Elem(), x, opt); err != nil {
-							return err
+							return &CellError{Row: row, Column: column, Value: x, Err: err}
 						}
 						elems = reflect.Append(elems, elem)
 					}
@@ -330,13 +436,12 @@ func (dec *decoder) decode(v reflect.Value, row, column int, opt *option) error
 					if rows.length() != 0 {
 						size := rows.list[rows.length()-1]
 						for i := 0; i <= size; i++ {
-							x := dec.getValue(row+i, column)
 							elem := reflect.New(v.Type().Elem().Elem())
-							if x == "" {
+							if x := dec.getValue(row+i, column); x == "" {
 								elems = reflect.Append(elems, reflect.New(v.Type().Elem()).Elem())
 								continue
 							}
-							if err := dec.set(elem.Elem(), x, opt); err != nil {
+							if err := dec.setAt(elem.Elem(), row+i, column, opt); err != nil {
 								return err
 							}
 							elems = reflect.Append(elems, elem)
@@ -347,9 +452,9 @@ func (dec *decoder) decode(v reflect.Value, row, column int, opt *option) error
 			}
 		case reflect.Struct:
 			rows := dec.targetRows(row, column)
-			for _, i := range rows.list {
+			for k := range rows.list {
 				elem := reflect.New(v.Type().Elem()).Elem()
-				if err := dec.decodeStruct(elem, row, column, i); err != nil {
+				if err := dec.decodeElem(elem, level, row, column, limit, rows, k); err != nil {
 					return err
 				}
 				elems = reflect.Append(elems, elem)
@@ -360,7 +465,7 @@ func (dec *decoder) decode(v reflect.Value, row, column int, opt *option) error
 				for _, x := range strings.Split(dec.getValue(row, column), ",") {
 					elem := reflect.New(v.Type().Elem()).Elem()
 					if err := dec.set(elem, x, opt); err != nil {
-						return err
+						return &CellError{Row: row, Column: column, Value: x, Err: err}
 					}
 					elems = reflect.Append(elems, elem)
 				}
@@ -369,9 +474,8 @@ func (dec *decoder) decode(v reflect.Value, row, column int, opt *option) error
 				if rows.length() != 0 {
 					size := rows.list[rows.length()-1]
 					for i := 0; i <= size; i++ {
-						x := dec.getValue(row+i, column)
 						elem := reflect.New(v.Type().Elem()).Elem()
-						if err := dec.set(elem, x, opt); err != nil {
+						if err := dec.setAt(elem, row+i, column, opt); err != nil {
 							return err
 						}
 						elems = reflect.Append(elems, elem)
@@ -382,42 +486,93 @@ func (dec *decoder) decode(v reflect.Value, row, column int, opt *option) error
 		}
 		v.Set(elems)
 	default:
-		x := dec.getValue(row, column)
-		if err := dec.set(v, x, opt); err != nil {
+		if err := dec.setAt(v, row, column, opt); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (dec *decoder) decodeStruct(v reflect.Value, row, column, idx int) error {
-	l := 1
-	for _, format := range dec.formats[row][column+1:] {
-		if format != "" {
-			break
+// decodeHorizontal level+1行目の番号のキーの位置から要素をdecodeする
+func (dec *decoder) decodeHorizontal(v reflect.Value, level, row, column, limit int, opt *option) error {
+	elem := opt.elem()
+	if v.Kind() == reflect.Slice {
+		v.Set(reflect.MakeSlice(v.Type(), 0, 1))
+	}
+	for i := column; i < limit; i++ {
+		// 要素は1列以上を使うので、範囲の列数より大きい番号は不正なヘッダー
+		n, err := strconv.Atoi(dec.format(level+1, i))
+		if err != nil || n < 1 || n > limit-column {
+			continue
+		}
+		end := dec.span(level+1, i, limit)
+		if v.Kind() == reflect.Array {
+			if n > v.Len() {
+				continue
+			}
+		} else {
+			// 値が無い末尾の要素は増やさない
+			if !dec.exists(level+1, row, i, end) {
+				continue
+			}
+			for v.Len() < n {
+				v.Set(reflect.Append(v, reflect.New(v.Type().Elem()).Elem()))
+			}
+		}
+		if err := dec.decode(v.Index(n-1), level+1, row, i, end, elem); err != nil {
+			return err
 		}
-		l++
 	}
-	name := v.Type().String()
-	for i, key := range dec.formats[row+1][column : column+l] {
+	return nil
+}
+
+// decodeInterface _typeの列の名前から具象型を決めてdecodeする
+func (dec *decoder) decodeInterface(v reflect.Value, level, row, column, limit int) error {
+	u := lookupUnion(v.Type())
+	name := dec.getValue(row, column)
+	if u == nil || name == "" {
+		return nil
+	}
+	t, isPtr, err := u.concrete(name)
+	if err != nil {
+		return &CellError{Row: row, Column: column, Value: name, Err: err}
+	}
+	elem := reflect.New(t)
+	if err := dec.decodeStruct(elem.Elem(), level, row, column, limit); err != nil {
+		return err
+	}
+	if isPtr {
+		v.Set(elem)
+	} else {
+		v.Set(elem.Elem())
+	}
+	return nil
+}
+
+// decodeStruct level+1行目のヘッダーのうちcolumn列目からlimit列目の手前までをフィールドとしてdecodeする
+func (dec *decoder) decodeStruct(v reflect.Value, level, row, column, limit int) error {
+	for i := column; i < limit; i++ {
+		key := dec.format(level+1, i)
 		if key == "" {
-			break
+			continue
 		}
 		keyIdx := strings.Index(key, ":")
 		var opt *option
 		if keyIdx > 0 && keyIdx+1 < len(key) {
 			// option
-			opt = newOption(key[keyIdx+1:], false)
+			opt = newOption(key[keyIdx+1:])
 		}
 		if keyIdx > 0 {
 			key = key[:keyIdx]
 		}
-		key = dec.getIndex(name, key)
+		key = dec.getIndex(v.Type(), key)
+		// 埋め込みの構造体から昇格したフィールドや非公開のフィールドには代入しない
 		field, ok := v.Type().FieldByName(key)
-		if ok && field.Tag.Get(tagName) != "-" {
-			elem := v.FieldByName(key)
-			if elem.IsValid() {
-				if err := dec.decode(elem, row+idx, column+i, opt); err != nil {
+		if ok && len(field.Index) == 1 && field.Tag.Get(tagName) != "-" {
+			elem := v.Field(field.Index[0])
+			if elem.CanSet() {
+				if err := dec.decode(elem, level+1, row, i, dec.span(level+1, i, limit), opt); err != nil {
+					resetOption(opt)
 					return err
 				}
 			}
@@ -427,32 +582,139 @@ func (dec *decoder) decodeStruct(v reflect.Value, row, column, idx int) error {
 	return nil
 }
 
+// format level行目のcolumn列目のヘッダー、範囲外は空文字
+func (dec *decoder) format(level, column int) string {
+	if level < 0 || level >= len(dec.formats) || column < 0 || column >= len(dec.formats[level]) {
+		return ""
+	}
+	return dec.formats[level][column]
+}
+
+// span level行目のcolumn列目から始まるグループの終端の列、limitを超えない
+func (dec *decoder) span(level, column, limit int) int {
+	i := column + 1
+	for i < limit && dec.format(level, i) == "" {
+		i++
+	}
+	return i
+}
+
+// exists row行目のcolumn列目からlimit列目の手前までに_indexとコメントの列以外の値があるか
+func (dec *decoder) exists(level, row, column, limit int) bool {
+	for i := column; i < limit; i++ {
+		if dec.ignored[i] || dec.format(level+1, i) == indexKey {
+			continue
+		}
+		if x := dec.getValue(row, i); x != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeElem rows.list[k]行目の要素を次の要素の手前までの範囲でdecodeする
+func (dec *decoder) decodeElem(v reflect.Value, level, row, column, limit int, rows *rows, k int) error {
+	end := dec.end
+	if k+1 < rows.length() {
+		dec.end = row + rows.list[k+1]
+	}
+	err := dec.decodeStruct(v, level, row+rows.list[k], column, limit)
+	dec.end = end
+	return err
+}
+
+// targetRows row行目以降、レコードの終端までで値が入っている行のオフセット
 func (dec *decoder) targetRows(row, column int) *rows {
 	rows := getRowsPool()
-	for i := 0; i < len(dec.values); i++ {
-		if x := dec.getValue(row+i, column); x != "" {
-			rows.add(i)
-		}
+	list := dec.columnRows(column)
+	for i := sort.SearchInts(list, row); i < len(list) && list[i] < dec.end; i++ {
+		rows.add(list[i] - row)
 	}
 	return rows
 }
 
+func (dec *decoder) columnRows(column int) []int {
+	if list, ok := dec.columns[column]; ok {
+		return list
+	}
+	var list []int
+	for i := range dec.values {
+		if x := dec.getValue(i, column); x != "" {
+			list = append(list, i)
+		}
+	}
+	dec.columns[column] = list
+	return list
+}
+
 func (dec *decoder) getValue(row, column int) string {
 	if row < len(dec.values) && column < len(dec.values[row]) {
-		return dec.values[row][column]
+		if x := dec.values[row][column]; x != dec.literal {
+			return x
+		}
 	}
 	return ""
 }
 
+func (dec *decoder) getTyped(row, column int) interface{} {
+	if dec.getValue(row, column) == "" {
+		return nil
+	}
+	if row < len(dec.typed) && column < len(dec.typed[row]) {
+		return dec.typed[row][column]
+	}
+	return nil
+}
+
+// setAt セルの値を代入する、型付きの値があれば文字列を経由しない
+func (dec *decoder) setAt(v reflect.Value, row, column int, opt *option) error {
+	value := dec.getValue(row, column)
+	if x := dec.getTyped(row, column); x != nil && isNullable(v.Type()) {
+		if err := scanNullable(v, x); err != nil {
+			return &CellError{Row: row, Column: column, Value: value, Err: err}
+		}
+		return nil
+	}
+	if x := dec.getTyped(row, column); x != nil && lookupEnum(v.Type()) == nil {
+		if _, ok := x.(string); !ok {
+			ok, err := assign(v, x, opt)
+			if err != nil {
+				return &CellError{Row: row, Column: column, Value: value, Err: err}
+			}
+			if ok {
+				return nil
+			}
+		}
+	}
+	if err := dec.set(v, value, opt); err != nil {
+		return &CellError{Row: row, Column: column, Value: value, Err: err}
+	}
+	return nil
+}
+
 func (dec *decoder) set(v reflect.Value, value string, opt *option) error {
 	if value == "" {
 		return nil
 	}
+	if isNullable(v.Type()) {
+		return scanNullable(v, value)
+	}
+	if e := lookupEnum(v.Type()); e != nil {
+		return e.set(v, value)
+	}
 	switch v.Kind() {
+	case reflect.Struct:
+		if v.Type() == typeOfTime {
+			t, err := decodeDatetime(value, opt)
+			if err != nil {
+				return err
+			}
+			v.Set(reflect.ValueOf(t))
+		}
 	case reflect.String:
 		v.SetString(value)
 	case reflect.Bool:
-		x, err := strconv.ParseBool(value)
+		x, err := dec.parseBool(value, opt)
 		if err != nil {
 			return err
 		}
@@ -463,23 +725,33 @@ func (dec *decoder) set(v reflect.Value, value string, opt *option) error {
 			if err != nil {
 				return err
 			}
-			value = strconv.FormatInt(t.Unix(), 10)
+			value = strconv.FormatInt(toUnix(t, opt), 10)
 		}
-		x, err := strconv.ParseInt(value, 10, 64)
+		x, err := parseInt(value, v.Type().Bits(), dec.tolerant)
 		if err != nil {
-			return err
+			return rangeError(err, value, v.Type())
 		}
 		v.SetInt(x)
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		x, err := strconv.ParseUint(value, 10, 64)
+		if opt != nil && opt.isDatetime {
+			t, err := decodeDatetime(value, opt)
+			if err != nil {
+				return err
+			}
+			value = strconv.FormatInt(toUnix(t, opt), 10)
+		}
+		x, err := parseUint(value, v.Type().Bits(), dec.tolerant)
 		if err != nil {
-			return err
+			return rangeError(err, value, v.Type())
 		}
 		v.SetUint(x)
 	case reflect.Float32, reflect.Float64:
-		x, err := strconv.ParseFloat(value, 64)
+		if dec.tolerant {
+			value = normalizeNumber(value)
+		}
+		x, err := strconv.ParseFloat(value, v.Type().Bits())
 		if err != nil {
-			return err
+			return rangeError(err, value, v.Type())
 		}
 		v.SetFloat(x)
 	}
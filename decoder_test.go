@@ -3,6 +3,9 @@ package sheet
 import (
 	"fmt"
 	"github.com/k0kubun/pp"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -44,6 +47,323 @@ func TestNewDecoder(t *testing.T) {
 	pp.Println(sample)
 }
 
+type SampleNested struct {
+	ID    string             `sheet:"id,index"`
+	Items []SampleNestedItem `sheet:"items"`
+}
+
+type SampleNestedItem struct {
+	Code string   `sheet:"code"`
+	Tags []string `sheet:"tags"`
+}
+
+func TestDecoderNestedSlice(t *testing.T) {
+	formats := [][]string{
+		{"id", "items", "", ""},
+		{"", "_index", "code", "tags"},
+	}
+	values := [][]string{
+		{"id_01", "1", "code_01", "A"},
+		{"", "", "", "B"},
+		{"", "2", "code_02", "C"},
+	}
+	sample := &SampleNested{}
+	if err := newDecoder(formats).Decode(values, sample); err != nil {
+		t.Fatal(err)
+	}
+	expected := &SampleNested{
+		ID: "id_01",
+		Items: []SampleNestedItem{
+			{Code: "code_01", Tags: []string{"A", "B"}},
+			{Code: "code_02", Tags: []string{"C"}},
+		},
+	}
+	if !reflect.DeepEqual(sample, expected) {
+		t.Errorf("decode %+v, expected %+v", sample, expected)
+	}
+}
+
+type SampleDeep struct {
+	ID    string       `sheet:"id"`
+	Item  *SampleItem  `sheet:"item"`
+	Items []SampleItem `sheet:"items"`
+	Num   int          `sheet:"num"`
+}
+
+func TestDecoderDeepHeader(t *testing.T) {
+	sample := &SampleDeep{
+		ID: "id_01",
+		Item: &SampleItem{
+			Code:  "code_01",
+			Hoge:  SampleHoge{Title: "title_01", Order: 1},
+			Slice: SampleSlicePtr{List: []string{"A", "B"}},
+		},
+		Items: []SampleItem{
+			{Code: "code_02", GraphID: 2, Hoge: SampleHoge{Title: "title_02"}, Slice: SampleSlicePtr{List: []string{"C"}}},
+			{Code: "code_03", Hoge: SampleHoge{Order: 3}, Slice: SampleSlicePtr{List: []string{"D"}}},
+		},
+		Num: 10,
+	}
+	formats, err := Header(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(formats) != 3 {
+		t.Fatalf("header rows %d, expected 3", len(formats))
+	}
+	values, err := Marshal(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := &SampleDeep{}
+	if err := UnmarshalValues(formats, values, actual); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(actual, sample) {
+		t.Errorf("decode %+v, expected %+v", actual, sample)
+	}
+
+	// ヘッダーの行が足りない場合は読めるところまでdecodeする
+	actual = &SampleDeep{}
+	if err := Unmarshal(formats[:1], [][]string{{"id_01", "code_01"}}, actual); err != nil {
+		t.Fatal(err)
+	}
+	if actual.ID != "id_01" {
+		t.Errorf("decode %+v", actual)
+	}
+}
+
+func TestDecoderCommentColumn(t *testing.T) {
+	formats := [][]string{
+		{"# memo", "id", "sub", "", "", "num", "slist", "", "", "", "// note"},
+		{"", "", "code", "#memo", "num", "", "_index", "code", "", "num", ""},
+	}
+	values := [][]string{
+		{"memo_01", "id_01", "code_01", "memo_02", "1", "2", "1", "code_02", "gap", "3", "note_01"},
+		{"memo_03", "", "", "", "", "", "2", "code_03", "", "4", ""},
+	}
+	sample := &SampleUnmarshal{}
+	if err := Unmarshal(formats, values, sample, WithCommentColumn("#", "//")); err != nil {
+		t.Fatal(err)
+	}
+	expected := &SampleUnmarshal{
+		ID:  "id_01",
+		Sub: &SampleUnmarshalSub{Code: "code_01", Num: 1},
+		Num: 2,
+		SList: []SampleUnmarshalSub2{
+			{Code: "code_02", Num: 3},
+			{Code: "code_03", Num: 4},
+		},
+	}
+	if !reflect.DeepEqual(sample, expected) {
+		t.Errorf("decode %+v, expected %+v", sample, expected)
+	}
+
+	// コメントの列にしか値が無い場合はnilのまま
+	values = [][]string{{"", "id_01", "", "memo", "", "", "", "", "", "", ""}}
+	sample = &SampleUnmarshal{}
+	if err := Unmarshal(formats, values, sample, WithCommentColumn("#", "//")); err != nil {
+		t.Fatal(err)
+	}
+	if sample.Sub != nil {
+		t.Errorf("decode %+v, expected nil", sample.Sub)
+	}
+}
+
+type SampleTitle struct {
+	ID   string     `sheet:"id,title=Item ID"`
+	Name string     `sheet:"name,title=名前,alias=label|caption"`
+	Hoge SampleHoge `sheet:"hoge,title=Hoge Detail"`
+}
+
+func TestDecoderTitleMatch(t *testing.T) {
+	formats := [][]string{
+		{" item  id ", "Caption", "HOGE detail", ""},
+		{"", "", "title", "ORDER"},
+	}
+	values := [][]string{{"id_01", "name_01", "title_01", "1"}}
+	sample := &SampleTitle{}
+	if err := Unmarshal(formats, values, sample, WithTitleMatch()); err != nil {
+		t.Fatal(err)
+	}
+	expected := &SampleTitle{ID: "id_01", Name: "name_01", Hoge: SampleHoge{Title: "title_01", Order: 1}}
+	if !reflect.DeepEqual(sample, expected) {
+		t.Errorf("decode %+v, expected %+v", sample, expected)
+	}
+
+	// aliasはWithTitleMatchが無くても一致する
+	formats = [][]string{{"名前", "label"}}
+	sample = &SampleTitle{}
+	if err := Unmarshal(formats, [][]string{{"name_01", "name_02"}}, sample); err != nil {
+		t.Fatal(err)
+	}
+	if sample.Name != "name_02" {
+		t.Errorf("decode %+v", sample)
+	}
+}
+
+func TestDecoderIndex(t *testing.T) {
+	pA := "pa"
+	sample := &SampleMarshal{
+		ID:        "id_01",
+		Time:      time.Date(2017, 11, 6, 1, 27, 0, 0, time.Local),
+		List:      []string{"A", "B"},
+		Item:      &SampleItem{Code: "code_01", Slice: SampleSlicePtr{List: []string{"AA"}}},
+		Hoges:     []SampleHoge{{Title: "title_01", Order: 1}},
+		PList:     []*string{&pA, nil},
+		CreatedAt: time.Date(2017, 11, 6, 1, 27, 0, 0, time.Local).Unix(),
+		Floats:    []float32{1.5, 2.25},
+	}
+	formats, err := Header(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"Time:datetime", "List:csv", "CreatedAt:datetime", "Floats:csv"} {
+		found := false
+		for _, format := range formats[0] {
+			found = found || format == key
+		}
+		if !found {
+			t.Errorf("header %v, expected %s", formats[0], key)
+		}
+	}
+	values, err := Marshal(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := &SampleMarshal{}
+	if err := UnmarshalValues(formats, values, actual); err != nil {
+		t.Fatal(err)
+	}
+	actual.PList = append(actual.PList, nil)
+	if !reflect.DeepEqual(actual, sample) {
+		t.Errorf("decode %+v, expected %+v", actual, sample)
+	}
+
+	// 名前が同じ別の型のフィールドを取り違えない
+	type Item struct {
+		Code string `sheet:",csv"`
+	}
+	type first = Item
+	{
+		type Item struct {
+			Name string `sheet:"name"`
+		}
+		type Outer struct {
+			First  first `sheet:"first"`
+			Second Item  `sheet:"second"`
+		}
+		formats := [][]string{
+			{"first", "second"},
+			{"Code", "name"},
+		}
+		outer := &Outer{}
+		if err := Unmarshal(formats, [][]string{{"code_01", "name_01"}}, outer); err != nil {
+			t.Fatal(err)
+		}
+		if outer.First.Code != "code_01" || outer.Second.Name != "name_01" {
+			t.Errorf("decode %+v", outer)
+		}
+	}
+}
+
+type SampleValues struct {
+	ID    string    `sheet:"id"`
+	Int8  int8      `sheet:"int8"`
+	Uint  uint16    `sheet:"uint"`
+	Float float32   `sheet:"float"`
+	Bool  bool      `sheet:"bool"`
+	Time  time.Time `sheet:"time"`
+	Unix  int64     `sheet:"unix,datetime"`
+}
+
+func TestDecodeValues(t *testing.T) {
+	formats := [][]string{
+		{"id", "int8", "uint", "float", "bool", "time", "unix:datetime"},
+	}
+	now := time.Date(2017, 11, 6, 1, 27, 0, 0, time.Local)
+	values := [][]interface{}{
+		{"id_01", float64(-12), 300, 0.1, true, now, 43045.5},
+	}
+	sample := &SampleValues{}
+	if err := newDecoder(formats).DecodeValues(values, sample); err != nil {
+		t.Fatal(err)
+	}
+	expected := &SampleValues{
+		ID:    "id_01",
+		Int8:  -12,
+		Uint:  300,
+		Float: 0.1,
+		Bool:  true,
+		Time:  now,
+		Unix:  time.Date(2017, 11, 6, 12, 0, 0, 0, time.Local).Unix(),
+	}
+	if !reflect.DeepEqual(sample, expected) {
+		t.Errorf("decode %+v, expected %+v", sample, expected)
+	}
+
+	for _, row := range [][]interface{}{
+		{"", 300},
+		{"", 1.5},
+		{"", nil, -1},
+		{"", nil, nil, 1e40},
+	} {
+		if err := newDecoder(formats).DecodeValues([][]interface{}{row}, &SampleValues{}); err == nil {
+			t.Errorf("decode %v should fail", row)
+		}
+	}
+}
+
+type SampleNumbers struct {
+	Int8    int8    `sheet:"int8"`
+	Uint16  uint16  `sheet:"uint16"`
+	Int     int     `sheet:"int"`
+	Float32 float32 `sheet:"float32"`
+}
+
+func TestDecoderNumbers(t *testing.T) {
+	formats := [][]string{
+		{"int8", "uint16", "int", "float32"},
+	}
+	for _, row := range [][]string{
+		{"300"},
+		{"", "-1"},
+		{"", "65536"},
+		{"", "", "1.5"},
+		{"", "", "1,234"},
+		{"", "", "", "1e40"},
+	} {
+		err := newDecoder(formats).Decode([][]string{row}, &SampleNumbers{})
+		if _, ok := err.(*CellError); !ok {
+			t.Errorf("decode %v error %v, expected CellError", row, err)
+		}
+	}
+
+	sample := &SampleNumbers{}
+	values := [][]string{{"+12", "65,535", "1.0", "1,234.5"}}
+	if err := newDecoder(formats, WithTolerantNumbers()).Decode(values, sample); err != nil {
+		t.Fatal(err)
+	}
+	expected := &SampleNumbers{Int8: 12, Uint16: 65535, Int: 1, Float32: 1234.5}
+	if !reflect.DeepEqual(sample, expected) {
+		t.Errorf("decode %+v, expected %+v", sample, expected)
+	}
+	for _, row := range [][]string{
+		{"1,000"},
+		{"", "", "1.5"},
+		{"", "", "1,2,3"},
+		{"", "", ",,5"},
+		{"", "", "+-1"},
+		{"", "", "1234,567"},
+		{"", "", "", "1,2.5"},
+		{"", "", "", "1.000,5"},
+	} {
+		if err := newDecoder(formats, WithTolerantNumbers()).Decode([][]string{row}, &SampleNumbers{}); err == nil {
+			t.Errorf("decode %v should fail", row)
+		}
+	}
+}
+
 func BenchmarkNewDecoder(b *testing.B) {
 	formats := [][]string{
 		{"id", "sub", "", "num", "arr:csv", "pid", "list", "slist", "", "", "now:datetime"},
@@ -63,3 +383,119 @@ func BenchmarkNewDecoder(b *testing.B) {
 }
 
 // 100000	     14406 ns/op	    3369 B/op	      99 allocs/op
+
+func BenchmarkDecoderRows(b *testing.B) {
+	formats := [][]string{
+		{"id", "items", "", ""},
+		{"", "_index", "code", "tags"},
+	}
+	for _, n := range []int{1000, 10000, 100000} {
+		values := make([][]string, n)
+		for i := range values {
+			values[i] = []string{"", strconv.Itoa(i + 1), "code_" + strconv.Itoa(i), "tag"}
+		}
+		values[0][0] = "id_01"
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				sample := &SampleNested{}
+				if err := newDecoder(formats).Decode(values, sample); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+type SampleMalformed struct {
+	*SampleHoge
+	List [1]SampleHoge `sheet:"list"`
+	Tags []string      `sheet:"tags,horizontal=2"`
+	memo string
+}
+
+func TestDecoderMalformed(t *testing.T) {
+	for _, c := range []struct {
+		formats [][]string
+		values  [][]string
+	}{
+		{[][]string{{"list"}}, [][]string{{"1"}}},
+		{[][]string{{"list", ""}, {"_index", "Title"}}, [][]string{{"1", "A"}, {"2", "B"}, {"3", "C"}}},
+		{[][]string{{"Title", "memo"}}, [][]string{{"A", "B"}}},
+		{[][]string{{"tags:horizontal", ""}, {"99999999", "0"}}, [][]string{{"A", "B"}}},
+		{[][]string{{"", "list"}, {"", "", "", "_index"}}, [][]string{{"", "", "", "1"}}},
+		{[][]string{}, [][]string{{"A"}}},
+	} {
+		sample := &SampleMalformed{}
+		if err := Unmarshal(c.formats, c.values, sample); err != nil {
+			t.Errorf("decode %v: %v", c.formats, err)
+		}
+		if sample.SampleHoge != nil || sample.memo != "" || len(sample.Tags) != 0 {
+			t.Errorf("decode %v %+v", c.formats, sample)
+		}
+	}
+}
+
+// fuzzTargets 不正なグリッドでもpanicしないことを確かめる型
+func fuzzTargets() []func() interface{} {
+	return []func() interface{}{
+		func() interface{} { return &SampleUnmarshal{} },
+		func() interface{} { return &SampleMarshal{} },
+		func() interface{} { return &SampleDeep{} },
+		func() interface{} { return &SampleArrayPtr{} },
+		func() interface{} { return &SampleNumbers{} },
+		func() interface{} { return &SampleValues{} },
+		func() interface{} { return &SampleEnum{} },
+		func() interface{} { return &SampleNullable{} },
+		func() interface{} { return &SampleUnion{} },
+		func() interface{} { return &SampleNode{} },
+		func() interface{} { return &SampleHorizontal{} },
+		func() interface{} { return &SampleTitle{} },
+	}
+}
+
+// fuzzGrid 改行で行、タブで列に分ける
+func fuzzGrid(s string) [][]string {
+	var grid [][]string
+	for _, line := range strings.Split(s, "\n") {
+		grid = append(grid, strings.Split(line, "\t"))
+	}
+	return grid
+}
+
+func FuzzDecode(f *testing.F) {
+	for _, target := range fuzzTargets() {
+		formats, err := Header(target(), WithMaxDepth(2))
+		if err != nil {
+			f.Fatal(err)
+		}
+		header := make([]string, len(formats))
+		for i := range formats {
+			header[i] = strings.Join(formats[i], "\t")
+		}
+		f.Add(strings.Join(header, "\n"), "1\t2\t3\n\t4\t5", uint8(0))
+	}
+	f.Add("id\tsub\t\n\t_index\tcode", "id_01\t1\tA\n\t99999999\tB", uint8(1))
+	f.Add("rewards:horizontal\t\n99999999\t0", "1\t2", uint8(2))
+	f.Add("a.b.c:csv\t#memo\ta..b", "x\ty\tz", uint8(7))
+	f.Add("", "", uint8(0))
+
+	f.Fuzz(func(t *testing.T, header, body string, mode uint8) {
+		opts := []Option{WithMaxDepth(2)}
+		if mode&1 != 0 {
+			opts = append(opts, WithFlatHeader())
+		}
+		if mode&2 != 0 {
+			opts = append(opts, WithTranspose())
+		}
+		if mode&4 != 0 {
+			opts = append(opts, WithCommentColumn("#"), WithCommentPrefix("#"), WithTitleMatch(), WithTolerantNumbers())
+		}
+		formats, values := fuzzGrid(header), fuzzGrid(body)
+		for _, target := range fuzzTargets() {
+			_ = Unmarshal(formats, values, target(), opts...)
+			_ = UnmarshalSheet(append(formats, values...), target(), append(opts, WithHeaderDetection())...)
+		}
+	})
+}
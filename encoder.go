@@ -2,11 +2,12 @@ package sheet
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"reflect"
 	"strconv"
 	"sync"
 	"time"
-	"unicode"
 )
 
 var csvPool = sync.Pool{
@@ -45,6 +46,8 @@ type cell struct {
 	column int
 	row    int
 	value  interface{}
+	kind   CellKind
+	format string
 }
 
 type cells struct {
@@ -60,13 +63,29 @@ func (c *cells) truncate() {
 }
 
 type encoder struct {
+	*config
 	cells     *cells
 	maxColumn int
 	maxRow    int
+	// blank nilのポインタ、空のスライスの中の値の表現
+	blank Blank
+	// depth 展開中の構造体の型ごとの深さ
+	depth map[reflect.Type]int
+	// visited 展開中のポインタ
+	visited map[pointerKey]bool
 }
 
-func newEncoder() *encoder {
+// pointerKey 構造体と先頭のフィールドのように同じアドレスの値を型で区別する
+type pointerKey struct {
+	typ  reflect.Type
+	addr uintptr
+}
+
+func newEncoder(opts ...Option) *encoder {
 	return &encoder{
+		config:    newConfig(opts),
+		depth:     map[reflect.Type]int{},
+		visited:   map[pointerKey]bool{},
 		maxColumn: 0,
 		maxRow:    0,
 	}
@@ -74,6 +93,7 @@ func newEncoder() *encoder {
 
 func (enc *encoder) init() {
 	enc.cells = getCellPool()
+	enc.blank = enc.null.Nil
 	enc.maxColumn = 0
 	enc.maxRow = 0
 }
@@ -85,36 +105,80 @@ func (enc *encoder) reset() {
 func (enc *encoder) Encode(v interface{}) ([][]interface{}, error) {
 	enc.init()
 	defer enc.reset()
-	rv := reflect.ValueOf(v)
-	if rv.Kind() == reflect.Ptr && !rv.IsNil() {
-		rv = rv.Elem()
-	}
-	if _, err := enc.reflectStruct(rv, 0, 0, false); err != nil {
+	if err := enc.encode(reflect.ValueOf(v)); err != nil {
 		return nil, err
 	}
-	values := make([][]interface{}, enc.maxRow+1)
+	rows, columns := enc.size()
+	values := make([][]interface{}, rows)
 	for i := range values {
-		values[i] = make([]interface{}, enc.maxColumn+1)
+		values[i] = make([]interface{}, columns)
 	}
 	for _, cell := range enc.cells.list {
-		values[cell.row][cell.column] = cell.value
+		row, column := enc.position(cell)
+		values[row][column] = legacyValue(cell.value, cell.kind, cell.format)
 	}
 	return values, nil
 }
 
+func (enc *encoder) EncodeGrid(v interface{}) (*Grid, error) {
+	enc.init()
+	defer enc.reset()
+	if err := enc.encode(reflect.ValueOf(v)); err != nil {
+		return nil, err
+	}
+	rows, columns := enc.size()
+	cells := make([][]Cell, rows)
+	for i := range cells {
+		cells[i] = make([]Cell, columns)
+	}
+	for _, cell := range enc.cells.list {
+		row, column := enc.position(cell)
+		cells[row][column] = Cell{
+			Kind:   cell.kind,
+			Value:  cell.value,
+			Format: cell.format,
+		}
+	}
+	return &Grid{cells: cells}, nil
+}
+
+// size 出力する行数と列数、WithTransposeの場合は入れ替える
+func (enc *encoder) size() (int, int) {
+	if enc.transpose {
+		return enc.maxColumn + 1, enc.maxRow + 1
+	}
+	return enc.maxRow + 1, enc.maxColumn + 1
+}
+
+// position セルを出力する位置
+func (enc *encoder) position(c cell) (int, int) {
+	if enc.transpose {
+		return c.column, c.row
+	}
+	return c.row, c.column
+}
+
+func (enc *encoder) encode(rv reflect.Value) error {
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return errors.New("invalid encode error")
+	}
+	enc.depth[rv.Type()]++
+	_, err := enc.reflectStruct(rv, 0, 0, false)
+	enc.depth[rv.Type()]--
+	return err
+}
+
 func (enc *encoder) reflectStruct(v reflect.Value, column, row int, isNil bool) (int, error) {
+	fields, err := typeFields(v.Type(), enc.config)
+	if err != nil {
+		return 0, err
+	}
 	n := 0
-	for i := 0; i < v.Type().NumField(); i++ {
-		field := v.Type().Field(i)
-		if !unicode.IsUpper(rune(field.Name[0])) {
-			continue
-		}
-		tag := field.Tag.Get(tagName)
-		if tag == "-" {
-			continue
-		}
-		opt := newOption(tag, false)
-		addNum, err := enc.reflectValue(v.Field(i), column+n, row, opt, isNil)
+	for _, f := range fields {
+		addNum, err := enc.reflectValue(v.Field(f.index), column+n, row, f.opt, isNil)
 		if err != nil {
 			return 0, err
 		}
@@ -123,7 +187,6 @@ func (enc *encoder) reflectStruct(v reflect.Value, column, row int, isNil bool)
 		} else {
 			n++
 		}
-		resetOption(opt)
 	}
 	return n, nil
 }
@@ -132,7 +195,20 @@ func (enc *encoder) reflectList(v reflect.Value, isStruct bool, column, row int,
 	col := 0
 	if opt.isCSV && !isStruct {
 		buf := getCSVPool()
+		e := lookupEnum(v.Type().Elem())
 		for i := 0; i < v.Len(); i++ {
+			if e != nil {
+				label, err := e.label(v.Index(i))
+				if err != nil {
+					resetCSVPool(buf)
+					return 0, err
+				}
+				buf.WriteString(formatValue(label))
+				if i < v.Len()-1 {
+					buf.WriteString(",")
+				}
+				continue
+			}
 			switch v.Index(i).Kind() {
 			case reflect.String:
 				buf.WriteString(v.Index(i).String())
@@ -145,7 +221,7 @@ func (enc *encoder) reflectList(v reflect.Value, isStruct bool, column, row int,
 			case reflect.Float64:
 				buf.WriteString(strconv.FormatFloat(v.Index(i).Float(), 'e', -1, 64))
 			case reflect.Bool:
-				buf.WriteString(strconv.FormatBool(v.Index(i).Bool()))
+				buf.WriteString(formatValue(enc.formatBool(v.Index(i).Bool(), opt)))
 			}
 			if i < v.Len()-1 {
 				buf.WriteString(",")
@@ -173,13 +249,53 @@ func (enc *encoder) reflectList(v reflect.Value, isStruct bool, column, row int,
 }
 
 func (enc *encoder) reflectValue(v reflect.Value, column, row int, opt *option, isNil bool) (int, error) {
+	if isLeaf(v.Type()) {
+		if isNil && enc.blank != BlankZero {
+			enc.add(enc.blankValue(enc.blank), column, row)
+			return 0, nil
+		}
+		if !isNil && v.IsZero() {
+			if opt != nil && opt.omitEmpty {
+				enc.add(nil, column, row)
+				return 0, nil
+			}
+			if enc.null.Zero != BlankZero {
+				enc.add(enc.blankValue(enc.null.Zero), column, row)
+				return 0, nil
+			}
+		}
+	}
+	if isNullable(v.Type()) {
+		return 0, enc.addNullable(v, column, row, opt)
+	}
+	if e := lookupEnum(v.Type()); e != nil {
+		label, err := e.label(v)
+		if err != nil {
+			return 0, err
+		}
+		enc.add(label, column, row)
+		return 0, nil
+	}
 	switch v.Kind() {
 	case reflect.Ptr:
-		isNil = v.IsNil()
-		if isNil {
+		blank := enc.blank
+		if v.IsNil() {
+			if !isNil {
+				enc.blank = enc.null.Nil
+			}
+			isNil = true
 			v = reflect.New(v.Type().Elem())
+		} else {
+			// 循環参照しているポインタは展開しない
+			p := pointerKey{v.Type(), v.Pointer()}
+			if enc.visited[p] {
+				return 0, fmt.Errorf("sheet: cyclic pointer to %s", v.Type().Elem())
+			}
+			enc.visited[p] = true
+			defer delete(enc.visited, p)
 		}
 		n, err := enc.reflectValue(v.Elem(), column, row, opt, isNil)
+		enc.blank = blank
 		if err != nil {
 			return 0, err
 		}
@@ -188,28 +304,51 @@ func (enc *encoder) reflectValue(v reflect.Value, column, row int, opt *option,
 		switch v.Type() {
 		case typeOfTime:
 			if opt != nil && opt.isDatetime {
-				t, err := encodeDatetime(v)
+				t, err := encodeDatetime(v, opt)
 				if err != nil {
 					return 0, err
 				}
-				enc.add(t, column, row)
+				enc.addDatetime(t, datetimeLayout(opt), column, row)
 			} else {
 				val := v.Interface().(time.Time)
-				txt, err := val.MarshalText()
-				if err != nil {
+				// MarshalTextで表せない年はエラーにする
+				if _, err := val.MarshalText(); err != nil {
 					return 0, err
 				}
-				enc.add(string(txt), column, row)
+				enc.addDatetime(val, time.RFC3339Nano, column, row)
 			}
 		default:
+			t := v.Type()
+			if d := enc.depth[t]; d > 0 {
+				if enc.maxDepth == 0 {
+					return 0, fmt.Errorf("sheet: recursive type %s", t)
+				}
+				if d > enc.maxDepth {
+					if !isNil {
+						return 0, fmt.Errorf("sheet: %s exceeds max depth %d", t, enc.maxDepth)
+					}
+					enc.add(nil, column, row)
+					return 1, nil
+				}
+			}
+			enc.depth[t]++
 			n, err := enc.reflectStruct(v, column, row, isNil)
+			enc.depth[t]--
 			if err != nil {
 				return 0, err
 			}
 			return n, nil
 		}
+	case reflect.Interface:
+		return enc.reflectInterface(v, column, row, opt, isNil)
+	case reflect.Array, reflect.Slice:
+		if opt != nil && opt.isHorizontal {
+			return enc.reflectHorizontal(v, column, row, opt, isNil)
+		}
+	}
+	switch v.Kind() {
 	case reflect.Array:
-		isStruct := v.Type().Elem().Kind() == reflect.Struct
+		isStruct := layoutKind(v.Type().Elem()) == reflect.Struct
 		col, err := enc.reflectList(v, isStruct, column, row, opt, isNil)
 		if err != nil {
 			return 0, err
@@ -220,7 +359,7 @@ func (enc *encoder) reflectValue(v reflect.Value, column, row int, opt *option,
 		return col, nil
 	case reflect.Slice:
 		col := 0
-		isStruct := v.Type().Elem().Kind() == reflect.Struct
+		isStruct := layoutKind(v.Type().Elem()) == reflect.Struct
 		if v.Len() > 0 {
 			var err error
 			col, err = enc.reflectList(v, isStruct, column, row, opt, isNil)
@@ -234,7 +373,12 @@ func (enc *encoder) reflectValue(v reflect.Value, column, row int, opt *option,
 				enc.add(nil, column, row)
 				n = 1
 			}
+			blank := enc.blank
+			if !isNil {
+				enc.blank = enc.null.Empty
+			}
 			n, err := enc.reflectValue(v, column+n, row, opt, true)
+			enc.blank = blank
 			if err != nil {
 				return 0, err
 			}
@@ -248,17 +392,19 @@ func (enc *encoder) reflectValue(v reflect.Value, column, row int, opt *option,
 		return col, nil
 	}
 	if opt != nil && opt.isDatetime {
-		t, err := encodeDatetime(v)
+		t, err := encodeDatetime(v, opt)
 		if err != nil {
 			return 0, err
 		}
-		enc.add(t, column, row)
-	} else if isNil {
-		enc.add(nil, column, row)
+		enc.addDatetime(t, datetimeLayout(opt), column, row)
 	} else {
 		switch v.Kind() {
 		case reflect.String:
-			enc.add(v.String(), column, row)
+			if opt != nil && opt.isFormula {
+				enc.addCell(v.String(), CellFormula, "", column, row)
+			} else {
+				enc.add(v.String(), column, row)
+			}
 		case reflect.Int:
 			enc.add(int(v.Int()), column, row)
 		case reflect.Int8:
@@ -284,17 +430,147 @@ func (enc *encoder) reflectValue(v reflect.Value, column, row int, opt *option,
 		case reflect.Float64:
 			enc.add(v.Float(), column, row)
 		case reflect.Bool:
-			enc.add(v.Bool(), column, row)
+			enc.add(enc.formatBool(v.Bool(), opt), column, row)
 		}
 	}
 	return 0, nil
 }
 
+func (enc *encoder) blankValue(blank Blank) interface{} {
+	if blank == BlankLiteral {
+		return enc.nullLiteral()
+	}
+	return nil
+}
+
+// reflectHorizontal 要素を列方向に並べる、要素の幅はヘッダーと同じく型から決める
+func (enc *encoder) reflectHorizontal(v reflect.Value, column, row int, opt *option, isNil bool) (int, error) {
+	size, err := horizontalSize(v.Type(), opt)
+	if err != nil {
+		return 0, err
+	}
+	if v.Len() > size {
+		return 0, fmt.Errorf("sheet: %d elements overflow horizontal=%d", v.Len(), size)
+	}
+	elem := opt.elem()
+	h := &headerEncoder{config: enc.config, validations: map[int][]string{}, depth: map[reflect.Type]int{}}
+	width, err := h.encodeType(v.Type().Elem(), 0, 0, elem)
+	if err != nil {
+		return 0, err
+	}
+	for i := 0; i < size; i++ {
+		if i < v.Len() {
+			_, err = enc.reflectValue(v.Index(i), column+i*width, row, elem, isNil)
+		} else {
+			// 足りない要素は空のスライスと同じく扱う
+			blank := enc.blank
+			if !isNil {
+				enc.blank = enc.null.Empty
+			}
+			_, err = enc.reflectValue(reflect.New(v.Type().Elem()).Elem(), column+i*width, row, elem, true)
+			enc.blank = blank
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return size * width, nil
+}
+
+// reflectInterface 登録された具象型の名前と、その型のフィールドを対応する列に出力する
+func (enc *encoder) reflectInterface(v reflect.Value, column, row int, opt *option, isNil bool) (int, error) {
+	u := lookupUnion(v.Type())
+	if u == nil && (isNil || v.IsNil()) {
+		enc.add(nil, column, row)
+		return 0, nil
+	}
+	if u == nil {
+		// 登録されていないインターフェースはHeaderと同じく1つのセルに出力できる値に限る
+		if !isCellType(v.Elem().Type()) {
+			return 0, fmt.Errorf("sheet: value of %s in unregistered interface %s does not fit in a cell", v.Elem().Type(), v.Type())
+		}
+		return enc.reflectValue(v.Elem(), column, row, opt, false)
+	}
+	l, err := u.layout(enc.config)
+	if err != nil {
+		return 0, err
+	}
+	for i := 0; i < l.width; i++ {
+		enc.add(nil, column+i, row)
+	}
+	if isNil || v.IsNil() {
+		return l.width, nil
+	}
+	x := v.Elem()
+	name, ok := u.names[x.Type()]
+	if !ok {
+		return 0, fmt.Errorf("sheet: type %s is not registered for %s", x.Type(), v.Type())
+	}
+	enc.add(name, column, row)
+	if x.Kind() == reflect.Ptr {
+		if x.IsNil() {
+			return l.width, nil
+		}
+		x = x.Elem()
+	}
+	fields, err := typeFields(x.Type(), enc.config)
+	if err != nil {
+		return 0, err
+	}
+	for _, f := range fields {
+		col, ok := l.columns[f.key]
+		if !ok {
+			continue
+		}
+		if _, err := enc.reflectValue(x.Field(f.index), column+col, row, f.opt, false); err != nil {
+			return 0, err
+		}
+	}
+	return l.width, nil
+}
+
+// addNullable driver.Valuerの値を1つのセルに出力する、NULLの場合は空のセル
+func (enc *encoder) addNullable(v reflect.Value, column, row int, opt *option) error {
+	x, err := nullableValue(v)
+	if err != nil {
+		return err
+	}
+	switch x := x.(type) {
+	case nil:
+		enc.add(enc.blankValue(enc.null.Nil), column, row)
+	case time.Time:
+		t, err := encodeDatetime(reflect.ValueOf(x), opt)
+		if err != nil {
+			return err
+		}
+		enc.addDatetime(t, datetimeLayout(opt), column, row)
+	case []byte:
+		enc.add(string(x), column, row)
+	default:
+		enc.add(x, column, row)
+	}
+	return nil
+}
+
 func (enc *encoder) add(v interface{}, column, row int) {
+	enc.addCell(v, kindOf(v), "", column, row)
+}
+
+func (enc *encoder) addDatetime(v interface{}, format string, column, row int) {
+	if v == nil {
+		enc.add(nil, column, row)
+		return
+	}
+	enc.addCell(v, CellDatetime, format, column, row)
+}
+
+func (enc *encoder) addCell(v interface{}, kind CellKind, format string, column, row int) {
 	enc.cells.add(cell{
 		column: column,
 		row:    row,
 		value:  v,
+		kind:   kind,
+		format: format,
 	})
 	if enc.maxColumn < column {
 		enc.maxColumn = column
@@ -0,0 +1,68 @@
+package sheet
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+type enum struct {
+	// labels 値からラベル
+	labels map[interface{}]string
+	// values ラベルから値
+	values map[string]reflect.Value
+	// list 入力規則に使うラベルの一覧
+	list []string
+}
+
+var (
+	enumsMu sync.RWMutex
+	enums   = map[reflect.Type]*enum{}
+)
+
+// RegisterEnum 型Tの値とシート上のラベルの対応を登録する
+func RegisterEnum[T comparable](labels map[T]string) {
+	e := &enum{
+		labels: make(map[interface{}]string, len(labels)),
+		values: make(map[string]reflect.Value, len(labels)),
+		list:   make([]string, 0, len(labels)),
+	}
+	for v, label := range labels {
+		e.labels[v] = label
+		e.values[label] = reflect.ValueOf(v)
+		e.list = append(e.list, label)
+	}
+	sort.Strings(e.list)
+
+	enumsMu.Lock()
+	enums[reflect.TypeOf((*T)(nil)).Elem()] = e
+	enumsMu.Unlock()
+	resetTypeInfos()
+}
+
+func lookupEnum(t reflect.Type) *enum {
+	enumsMu.RLock()
+	defer enumsMu.RUnlock()
+	return enums[t]
+}
+
+// label 登録されていない値はゼロ値ならnil、それ以外はエラー
+func (e *enum) label(v reflect.Value) (interface{}, error) {
+	if label, ok := e.labels[v.Interface()]; ok {
+		return label, nil
+	}
+	if v.IsZero() {
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown %s value %v", v.Type(), v.Interface())
+}
+
+func (e *enum) set(v reflect.Value, label string) error {
+	x, ok := e.values[label]
+	if !ok {
+		return fmt.Errorf("unknown %s label %q", v.Type(), label)
+	}
+	v.Set(x)
+	return nil
+}
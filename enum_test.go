@@ -0,0 +1,95 @@
+package sheet
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type Rarity int
+
+const (
+	RarityCommon Rarity = iota + 1
+	RarityRare
+	RarityEpic
+)
+
+type SampleEnum struct {
+	ID      string   `sheet:"id"`
+	Rarity  Rarity   `sheet:"rarity"`
+	Rarity2 *Rarity  `sheet:"rarity2"`
+	List    []Rarity `sheet:"list,csv"`
+}
+
+type validationWriter struct {
+	rows        [][]interface{}
+	validations map[int][]string
+}
+
+func (w *validationWriter) WriteRow(row []interface{}) error {
+	w.rows = append(w.rows, append([]interface{}{}, row...))
+	return nil
+}
+
+func (w *validationWriter) SetValidation(column int, list []string) error {
+	w.validations[column] = list
+	return nil
+}
+
+func init() {
+	RegisterEnum(map[Rarity]string{
+		RarityCommon: "Common",
+		RarityRare:   "Rare",
+		RarityEpic:   "Epic",
+	})
+}
+
+func TestEnum(t *testing.T) {
+	sample := &SampleEnum{
+		ID:     "id_01",
+		Rarity: RarityEpic,
+		List:   []Rarity{RarityRare, RarityCommon},
+	}
+	values, err := Marshal(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]interface{}{{"id_01", "Epic", nil, "Rare,Common"}}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("marshal %v, expected %v", values, expected)
+	}
+
+	formats, err := Header(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := &SampleEnum{}
+	if err := Unmarshal(formats, [][]string{{"id_01", "Epic", "", "Rare,Common"}}, actual); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(actual, sample) {
+		t.Errorf("unmarshal %+v, expected %+v", actual, sample)
+	}
+
+	err = Unmarshal(formats, [][]string{{"id_01", "Legend"}}, &SampleEnum{})
+	var cellErr *CellError
+	if !errors.As(err, &cellErr) || cellErr.Column != 1 {
+		t.Errorf("unmarshal error %v, expected CellError at column 1", err)
+	}
+
+	if _, err := Marshal(&SampleEnum{Rarity: Rarity(10)}); err == nil {
+		t.Error("marshal unknown enum value should fail")
+	}
+}
+
+func TestEnumValidation(t *testing.T) {
+	w := &validationWriter{validations: map[int][]string{}}
+	if err := NewStreamEncoder(w, WithDataValidation()).Encode(&SampleEnum{Rarity: RarityRare}); err != nil {
+		t.Fatal(err)
+	}
+	list := []string{"Common", "Epic", "Rare"}
+	expected := map[int][]string{1: list, 2: list}
+	if !reflect.DeepEqual(w.validations, expected) {
+		t.Errorf("validations %v, expected %v", w.validations, expected)
+	}
+}
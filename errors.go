@@ -0,0 +1,20 @@
+package sheet
+
+import "fmt"
+
+// CellError decode時に失敗したセルの位置と値を持つエラー
+// 位置は0から始まる渡した値の行と列、UnmarshalSheetではシートの行と列
+type CellError struct {
+	Row    int
+	Column int
+	Value  string
+	Err    error
+}
+
+func (e *CellError) Error() string {
+	return fmt.Sprintf("sheet: row %d, column %d %q: %v", e.Row, e.Column, e.Value, e.Err)
+}
+
+func (e *CellError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,124 @@
+package sheet
+
+import (
+	"strconv"
+	"strings"
+)
+
+// pathSeparator フラットなヘッダーで階層を区切る文字
+const pathSeparator = "."
+
+// WithFlatHeader ヘッダーを1行にし、入れ子のフィールドをitem.hoge.titleのようなパスで表す
+// 構造体のスライスはhoges._index, hoges.titleの列に要素ごとの行を重ねる
+func WithFlatHeader() Option {
+	return func(c *config) {
+		c.flat = true
+	}
+}
+
+// flattenHeader 複数行のヘッダーを列ごとのパスにする
+// 途中のキーのオプションはパスの末尾のオプションにまとめる
+func flattenHeader(formats [][]string) []string {
+	if len(formats) == 0 {
+		return []string{}
+	}
+	dec := &decoder{formats: formats}
+	flat := make([]string, len(formats[0]))
+	var walk func(level, column, limit int, path, opts []string)
+	walk = func(level, column, limit int, path, opts []string) {
+		for i := column; i < limit; i++ {
+			key := dec.format(level, i)
+			if key == "" {
+				continue
+			}
+			p, o := path, opts
+			if idx := strings.Index(key, ":"); idx > 0 {
+				key, o = key[:idx], appendOptions(o, strings.Split(key[idx+1:], ","))
+			}
+			p = append(p[:len(p):len(p)], key)
+			flat[i] = strings.Join(p, pathSeparator)
+			if len(o) > 0 {
+				flat[i] += ":" + strings.Join(o, ",")
+			}
+			walk(level+1, i, dec.span(level, i, limit), p, o)
+		}
+	}
+	walk(0, 0, len(flat), nil, nil)
+	return flat
+}
+
+// appendOptions 重複しないようにオプションを追加する
+func appendOptions(opts, add []string) []string {
+	ret := opts[:len(opts):len(opts)]
+	for _, opt := range add {
+		found := false
+		for _, x := range ret {
+			found = found || x == opt
+		}
+		if !found && opt != "" {
+			ret = append(ret, opt)
+		}
+	}
+	return ret
+}
+
+// stackHeader パスのヘッダーを1階層1行のヘッダーにする
+// horizontalは番号のキーの親のキーに、それ以外のオプションは末尾のキーに付ける
+func stackHeader(flat []string) [][]string {
+	paths := make([][]string, len(flat))
+	depth := 1
+	for i, format := range flat {
+		if format == "" {
+			continue
+		}
+		path, opts := format, ""
+		if idx := strings.Index(format, ":"); idx > 0 {
+			path, opts = format[:idx], format[idx+1:]
+		}
+		paths[i] = strings.Split(path, pathSeparator)
+		var leaf []string
+		isHorizontal := false
+		for _, opt := range strings.Split(opts, ",") {
+			if opt == "horizontal" {
+				isHorizontal = true
+			} else if opt != "" {
+				leaf = append(leaf, opt)
+			}
+		}
+		last := len(paths[i]) - 1
+		if isHorizontal {
+			for j := 0; j < last; j++ {
+				if _, err := strconv.Atoi(paths[i][j+1]); err == nil {
+					paths[i][j] += ":horizontal"
+				}
+			}
+		}
+		if len(leaf) > 0 {
+			paths[i][last] += ":" + strings.Join(leaf, ",")
+		}
+		if depth < len(paths[i]) {
+			depth = len(paths[i])
+		}
+	}
+
+	formats := make([][]string, depth)
+	for i := range formats {
+		formats[i] = make([]string, len(flat))
+	}
+	var prev []string
+	for i, path := range paths {
+		if path == nil {
+			continue
+		}
+		// 直前の列と親が同じ間は同じグループとして空のセルにする
+		same := true
+		for level, key := range path {
+			same = same && level < len(prev) && prev[level] == key
+			if !same {
+				formats[level][i] = key
+			}
+		}
+		prev = path
+	}
+	return formats
+}
@@ -0,0 +1,117 @@
+package sheet
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFlatHeader(t *testing.T) {
+	sample := &SampleStream{
+		ID:  "id_01",
+		Num: 1,
+		Hoges: []SampleHoge{
+			{Title: "title_1_1", Order: 1},
+			{Title: "title_1_2", Order: 2},
+		},
+	}
+	formats, err := Header(sample, WithFlatHeader())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]string{{"id", "num", "hoges._index", "hoges.Title", "hoges.Order"}}
+	if !reflect.DeepEqual(formats, expected) {
+		t.Errorf("header %v, expected %v", formats, expected)
+	}
+
+	values, err := Marshal(sample, WithFlatHeader())
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := &SampleStream{}
+	if err := UnmarshalValues(formats, values, actual, WithFlatHeader()); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(actual, sample) {
+		t.Errorf("unmarshal %+v, expected %+v", actual, sample)
+	}
+}
+
+func TestFlatHeaderDeep(t *testing.T) {
+	for _, v := range []interface{}{&SampleDeep{}, &SampleUnion{}, &SampleUnmarshal{}} {
+		formats, err := Header(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		flat, err := Header(v, WithFlatHeader())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(flat[0], flattenHeader(formats)) {
+			t.Errorf("flat header %v, expected %v", flat[0], flattenHeader(formats))
+		}
+		if actual := stackHeader(flat[0]); !reflect.DeepEqual(actual, formats) {
+			t.Errorf("stack header %v, expected %v", actual, formats)
+		}
+	}
+
+	flat := []string{"id", "item.Code", "item.Hoge.Title", "item.Hoge.Order", "item.Slice.List:csv", "num"}
+	sample := &SampleDeep{}
+	values := [][]string{{"id_01", "code_01", "title_01", "1", "A,B", "2"}}
+	if err := Unmarshal([][]string{flat}, values, sample, WithFlatHeader()); err != nil {
+		t.Fatal(err)
+	}
+	expected := &SampleDeep{
+		ID: "id_01",
+		Item: &SampleItem{
+			Code:  "code_01",
+			Hoge:  SampleHoge{Title: "title_01", Order: 1},
+			Slice: SampleSlicePtr{List: []string{"A", "B"}},
+		},
+		Num: 2,
+	}
+	if !reflect.DeepEqual(sample, expected) {
+		t.Errorf("unmarshal %+v, expected %+v", sample, expected)
+	}
+}
+
+func TestFlatHeaderHorizontal(t *testing.T) {
+	sample := &SampleHorizontal{
+		ID:      "id_01",
+		Rewards: [2]SampleHoge{{Title: "title_01", Order: 1}, {Title: "title_02", Order: 2}},
+		Tags:    []string{"A", "B"},
+		Num:     10,
+	}
+	formats, err := Header(sample, WithFlatHeader())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]string{{
+		"id",
+		"rewards.1.Title:horizontal", "rewards.1.Order:horizontal",
+		"rewards.2.Title:horizontal", "rewards.2.Order:horizontal",
+		"tags.1:horizontal", "tags.2:horizontal", "tags.3:horizontal",
+		"num",
+	}}
+	if !reflect.DeepEqual(formats, expected) {
+		t.Errorf("header %v, expected %v", formats, expected)
+	}
+	stacked, err := Header(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual := stackHeader(formats[0]); !reflect.DeepEqual(actual, stacked) {
+		t.Errorf("stack header %v, expected %v", actual, stacked)
+	}
+
+	values, err := Marshal(sample, WithFlatHeader())
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := &SampleHorizontal{}
+	if err := UnmarshalValues(formats, values, actual, WithFlatHeader()); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(actual, sample) {
+		t.Errorf("unmarshal %+v, expected %+v", actual, sample)
+	}
+}
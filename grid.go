@@ -0,0 +1,119 @@
+package sheet
+
+import "time"
+
+// CellKind セルの値の種類
+type CellKind int
+
+const (
+	CellEmpty CellKind = iota
+	CellString
+	CellNumber
+	CellBool
+	CellDatetime
+	CellFormula
+)
+
+func (k CellKind) String() string {
+	switch k {
+	case CellEmpty:
+		return "empty"
+	case CellString:
+		return "string"
+	case CellNumber:
+		return "number"
+	case CellBool:
+		return "bool"
+	case CellDatetime:
+		return "datetime"
+	case CellFormula:
+		return "formula"
+	}
+	return "unknown"
+}
+
+// Cell 種類と元の値、書式を持ったセル
+type Cell struct {
+	Kind CellKind
+	// Value Marshalと同じ値、CellDatetimeはtime.Time、CellFormulaは文字列
+	Value interface{}
+	// Format CellDatetimeをMarshalと同じ文字列にする時刻の書式
+	Format string
+}
+
+func (c Cell) IsEmpty() bool {
+	return c.Kind == CellEmpty
+}
+
+// Grid セルの2次元配列
+type Grid struct {
+	cells [][]Cell
+}
+
+func NewGrid(cells [][]Cell) *Grid {
+	return &Grid{cells: cells}
+}
+
+func (g *Grid) Rows() int {
+	return len(g.cells)
+}
+
+func (g *Grid) Columns() int {
+	n := 0
+	for i := range g.cells {
+		if n < len(g.cells[i]) {
+			n = len(g.cells[i])
+		}
+	}
+	return n
+}
+
+// Cell 範囲外は空のセルを返す
+func (g *Grid) Cell(row, column int) Cell {
+	if row < 0 || column < 0 || row >= len(g.cells) || column >= len(g.cells[row]) {
+		return Cell{}
+	}
+	return g.cells[row][column]
+}
+
+func (g *Grid) Row(row int) []Cell {
+	if row < 0 || row >= len(g.cells) {
+		return nil
+	}
+	return g.cells[row]
+}
+
+// Values Marshalと同じ形式の値を返す
+func (g *Grid) Values() [][]interface{} {
+	values := make([][]interface{}, len(g.cells))
+	for i := range g.cells {
+		values[i] = make([]interface{}, len(g.cells[i]))
+		for j := range g.cells[i] {
+			c := g.cells[i][j]
+			values[i][j] = legacyValue(c.Value, c.Kind, c.Format)
+		}
+	}
+	return values
+}
+
+// legacyValue Marshalの値、CellDatetimeの時刻はFormatの文字列にする
+func legacyValue(v interface{}, kind CellKind, format string) interface{} {
+	if t, ok := v.(time.Time); ok && kind == CellDatetime {
+		return t.Format(format)
+	}
+	return v
+}
+
+func kindOf(v interface{}) CellKind {
+	switch v.(type) {
+	case nil:
+		return CellEmpty
+	case string:
+		return CellString
+	case bool:
+		return CellBool
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return CellNumber
+	}
+	return CellString
+}
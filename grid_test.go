@@ -0,0 +1,55 @@
+package sheet
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type SampleGrid struct {
+	ID    string    `sheet:"id"`
+	Num   int8      `sheet:"num"`
+	PID   *string   `sheet:"pid"`
+	Bool  bool      `sheet:"bool"`
+	Time  time.Time `sheet:"time,datetime"`
+	Total string    `sheet:"total,formula"`
+}
+
+func TestMarshalGrid(t *testing.T) {
+	sample := &SampleGrid{
+		ID:    "id_01",
+		Num:   12,
+		Bool:  true,
+		Time:  time.Date(2017, 11, 6, 1, 27, 0, 0, time.Local),
+		Total: "=SUM(B1:B2)",
+	}
+	grid, err := MarshalGrid(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Cell{
+		{Kind: CellString, Value: "id_01"},
+		{Kind: CellNumber, Value: int8(12)},
+		{Kind: CellEmpty},
+		{Kind: CellBool, Value: true},
+		{Kind: CellDatetime, Value: sample.Time, Format: timeFormat},
+		{Kind: CellFormula, Value: "=SUM(B1:B2)"},
+	}
+	if grid.Rows() != 1 || grid.Columns() != len(expected) {
+		t.Fatalf("grid size %dx%d", grid.Rows(), grid.Columns())
+	}
+	if !reflect.DeepEqual(grid.Row(0), expected) {
+		t.Errorf("grid %v, expected %v", grid.Row(0), expected)
+	}
+	if !grid.Cell(3, 3).IsEmpty() {
+		t.Errorf("out of range cell %v", grid.Cell(3, 3))
+	}
+
+	values, err := Marshal(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(grid.Values(), values) {
+		t.Errorf("grid values %v, expected %v", grid.Values(), values)
+	}
+}
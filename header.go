@@ -1,9 +1,15 @@
 package sheet
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
-	"unicode"
+	"strconv"
+)
+
+const (
+	indexKey = "_index"
+	typeKey  = "_type"
 )
 
 type headerCell struct {
@@ -14,45 +20,174 @@ type headerCell struct {
 }
 
 type headerEncoder struct {
-	cells []headerCell
+	*config
+	cells     []headerCell
+	maxColumn int
+	maxRow    int
+	// validations 列ごとの入力規則のリスト
+	validations map[int][]string
+	// depth 展開中の構造体の型ごとの深さ
+	depth map[reflect.Type]int
 }
 
-func newHeaderEncoder() *headerEncoder {
+func newHeaderEncoder(opts ...Option) *headerEncoder {
 	return &headerEncoder{
-		cells: []headerCell{},
+		config:      newConfig(opts),
+		cells:       []headerCell{},
+		validations: map[int][]string{},
+		depth:       map[reflect.Type]int{},
 	}
 }
 
-func (enc *headerEncoder) Encode(v interface{}) {
+func (enc *headerEncoder) Encode(v interface{}) ([][]string, error) {
 	rv := reflect.ValueOf(v)
 	if rv.Kind() == reflect.Ptr && !rv.IsNil() {
 		rv = rv.Elem()
 	}
+	if rv.Kind() != reflect.Struct {
+		return nil, errors.New("invalid header error")
+	}
+
+	enc.cells = enc.cells[:0]
+	enc.maxColumn = 0
+	enc.maxRow = 0
+	if _, err := enc.encodeType(rv.Type(), 0, 0, nil); err != nil {
+		return nil, err
+	}
 
-	enc.encode(rv)
+	formats := make([][]string, enc.maxRow+1)
+	for i := range formats {
+		formats[i] = make([]string, enc.maxColumn+1)
+	}
+	for _, cell := range enc.cells {
+		formats[cell.row][cell.column] = cell.key
+	}
+	if enc.flat {
+		formats = [][]string{flattenHeader(formats)}
+	}
+	if enc.transpose {
+		formats = transpose(formats)
+	}
+	return formats, nil
 }
 
-func (enc *headerEncoder) encode(v reflect.Value) {
+// encode 構造体のフィールドをrow行目に並べ、使用した列数を返す
+func (enc *headerEncoder) encode(t reflect.Type, column, row int) (int, error) {
+	fields, err := typeFields(t, enc.config)
+	if err != nil {
+		return 0, err
+	}
 	n := 0
-	for i := 0; i < v.Type().NumField(); i++ {
-		field := v.Type().Field(i)
-		if !unicode.IsUpper(rune(field.Name[0])) {
-			continue
+	for _, f := range fields {
+		key := f.key
+		if opts := f.opt.format(); opts != "" {
+			key += ":" + opts
 		}
-		tag := field.Tag.Get(tagName)
-		if tag == "-" {
-			continue
+		enc.add(key, f.opt.title, column+n, row)
+		w, err := enc.encodeType(t.Field(f.index).Type, column+n, row+1, f.opt)
+		if err != nil {
+			return 0, err
 		}
-		key := field.Name
-		opt := newOption(tag, true)
-		if opt.isDatetime {
-			key += ":datetime"
+		n += w
+	}
+	return n, nil
+}
+
+// encodeType encoderと同じ幅になるように型の列数を返す
+func (enc *headerEncoder) encodeType(t reflect.Type, column, row int, opt *option) (int, error) {
+	if e := lookupEnum(t); e != nil {
+		if opt == nil || !opt.isCSV {
+			enc.validations[column] = e.list
 		}
-		fmt.Println(key)
-		n++
+		return 1, nil
 	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return enc.encodeType(t.Elem(), column, row, opt)
+	case reflect.Interface:
+		if u := lookupUnion(t); u != nil {
+			l, err := u.layout(enc.config)
+			if err != nil {
+				return 0, err
+			}
+			enc.add(typeKey, "", column, row)
+			for _, f := range l.fields {
+				key := f.key
+				if opts := f.opt.format(); opts != "" {
+					key += ":" + opts
+				}
+				enc.add(key, f.opt.title, column+f.column, row)
+				if _, err := enc.encodeType(f.typ, column+f.column, row+1, f.opt); err != nil {
+					return 0, err
+				}
+			}
+			return l.width, nil
+		}
+	case reflect.Struct:
+		if isLeaf(t) {
+			return 1, nil
+		}
+		// 再帰的な型はWithMaxDepthの深さまで展開し、それ以降は1つのセルにする
+		if d := enc.depth[t]; d > 0 {
+			if enc.maxDepth == 0 {
+				return 0, fmt.Errorf("sheet: recursive type %s", t)
+			}
+			if d > enc.maxDepth {
+				return 1, nil
+			}
+		}
+		enc.depth[t]++
+		n, err := enc.encode(t, column, row)
+		enc.depth[t]--
+		if err != nil || n > 0 {
+			return n, err
+		}
+	case reflect.Array, reflect.Slice:
+		if opt != nil && opt.isHorizontal {
+			return enc.encodeHorizontal(t, column, row, opt)
+		}
+		if layoutKind(t.Elem()) == reflect.Struct {
+			enc.add(indexKey, "", column, row)
+			n, err := enc.encodeType(t.Elem(), column+1, row, opt)
+			return n + 1, err
+		}
+		if opt == nil || !opt.isCSV {
+			return enc.encodeType(t.Elem(), column, row, opt)
+		}
+	}
+	return 1, nil
+}
+
+// encodeHorizontal 要素ごとに1から始まる番号のキーを並べ、その下に要素のヘッダーを置く
+func (enc *headerEncoder) encodeHorizontal(t reflect.Type, column, row int, opt *option) (int, error) {
+	size, err := horizontalSize(t, opt)
+	if err != nil {
+		return 0, err
+	}
+	elem := opt.elem()
+	n := 0
+	for i := 0; i < size; i++ {
+		enc.add(strconv.Itoa(i+1), "", column+n, row)
+		w, err := enc.encodeType(t.Elem(), column+n, row+1, elem)
+		if err != nil {
+			return 0, err
+		}
+		n += w
+	}
+	return n, nil
 }
 
-func (enc *headerEncoder) add(v reflect.Value, column, row int) {
-	enc.cells = append(enc.cells, headerCell{})
+func (enc *headerEncoder) add(key, title string, column, row int) {
+	enc.cells = append(enc.cells, headerCell{
+		column: column,
+		row:    row,
+		key:    key,
+		title:  title,
+	})
+	if enc.maxColumn < column {
+		enc.maxColumn = column
+	}
+	if enc.maxRow < row {
+		enc.maxRow = row
+	}
 }
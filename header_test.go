@@ -1,6 +1,9 @@
 package sheet
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+)
 
 type SampleHeader struct {
 	ID        string
@@ -9,5 +12,28 @@ type SampleHeader struct {
 
 func TestNewHeaderEncoder(t *testing.T) {
 	sample := &SampleHeader{}
-	newHeaderEncoder().Encode(sample)
+	formats, err := newHeaderEncoder().Encode(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]string{
+		{"ID", "UpdatedAt:datetime"},
+	}
+	if !reflect.DeepEqual(formats, expected) {
+		t.Errorf("header %v, expected %v", formats, expected)
+	}
+}
+
+func TestHeader(t *testing.T) {
+	formats, err := Header(&SampleUnmarshal{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]string{
+		{"id", "sub", "", "num", "arr:csv", "pid", "list", "slist", "", "", "now:datetime"},
+		{"", "code", "num", "", "", "", "", "_index", "code", "num", ""},
+	}
+	if !reflect.DeepEqual(formats, expected) {
+		t.Errorf("header %v, expected %v", formats, expected)
+	}
 }
@@ -0,0 +1,153 @@
+package sheet
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// Document ヘッダーと値をdiffしやすいテキストで保存するための形式
+type Document struct {
+	Headers [][]string      `json:"headers"`
+	Rows    [][]interface{} `json:"rows"`
+}
+
+type jsonDocument struct {
+	Headers [][]string          `json:"headers"`
+	Rows    [][]json.RawMessage `json:"rows"`
+}
+
+// MarshalJSON 値として持った場合も型付きの形式にするため値レシーバにする
+func (doc Document) MarshalJSON() ([]byte, error) {
+	x := &jsonDocument{
+		Headers: doc.Headers,
+		Rows:    make([][]json.RawMessage, len(doc.Rows)),
+	}
+	for i := range doc.Rows {
+		x.Rows[i] = make([]json.RawMessage, len(doc.Rows[i]))
+		for j := range doc.Rows[i] {
+			b, err := encodeJSONValue(doc.Rows[i][j])
+			if err != nil {
+				return nil, err
+			}
+			x.Rows[i][j] = b
+		}
+	}
+	return json.Marshal(x)
+}
+
+func (doc *Document) UnmarshalJSON(data []byte) error {
+	x := &jsonDocument{}
+	if err := json.Unmarshal(data, x); err != nil {
+		return err
+	}
+	doc.Headers = x.Headers
+	doc.Rows = make([][]interface{}, len(x.Rows))
+	for i := range x.Rows {
+		doc.Rows[i] = make([]interface{}, len(x.Rows[i]))
+		for j := range x.Rows[i] {
+			v, err := decodeJSONValue(x.Rows[i][j])
+			if err != nil {
+				return err
+			}
+			doc.Rows[i][j] = v
+		}
+	}
+	return nil
+}
+
+// Values Unmarshalに渡せる文字列の値に変換する
+func (doc *Document) Values() [][]string {
+	values := make([][]string, len(doc.Rows))
+	for i := range doc.Rows {
+		values[i] = make([]string, len(doc.Rows[i]))
+		for j := range doc.Rows[i] {
+			values[i][j] = formatValue(doc.Rows[i][j])
+		}
+	}
+	return values
+}
+
+// encodeJSONValue 数値は型が失われないように {"int8": 1} の形式にする
+func encodeJSONValue(v interface{}) ([]byte, error) {
+	switch x := v.(type) {
+	case nil, string, bool:
+		return json.Marshal(x)
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return json.Marshal(map[string]interface{}{
+			fmt.Sprintf("%T", x): x,
+		})
+	}
+	return nil, fmt.Errorf("unsupported json value type %T", v)
+}
+
+// decodeJSONValue 手で編集した素の数値は整数ならint64、それ以外はfloat64にする
+func decodeJSONValue(data json.RawMessage) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	switch x := v.(type) {
+	case nil, string, bool:
+		return x, nil
+	case json.Number:
+		if n, err := x.Int64(); err == nil {
+			return n, nil
+		}
+		return x.Float64()
+	case map[string]interface{}:
+		if len(x) != 1 {
+			break
+		}
+		for typ, num := range x {
+			n, ok := num.(json.Number)
+			if !ok {
+				break
+			}
+			return parseNumber(typ, n.String())
+		}
+	}
+	return nil, fmt.Errorf("invalid json value %s", string(data))
+}
+
+func parseNumber(typ, s string) (interface{}, error) {
+	switch typ {
+	case "int":
+		x, err := strconv.ParseInt(s, 10, strconv.IntSize)
+		return int(x), err
+	case "int8":
+		x, err := strconv.ParseInt(s, 10, 8)
+		return int8(x), err
+	case "int16":
+		x, err := strconv.ParseInt(s, 10, 16)
+		return int16(x), err
+	case "int32":
+		x, err := strconv.ParseInt(s, 10, 32)
+		return int32(x), err
+	case "int64":
+		return strconv.ParseInt(s, 10, 64)
+	case "uint":
+		x, err := strconv.ParseUint(s, 10, strconv.IntSize)
+		return uint(x), err
+	case "uint8":
+		x, err := strconv.ParseUint(s, 10, 8)
+		return uint8(x), err
+	case "uint16":
+		x, err := strconv.ParseUint(s, 10, 16)
+		return uint16(x), err
+	case "uint32":
+		x, err := strconv.ParseUint(s, 10, 32)
+		return uint32(x), err
+	case "uint64":
+		return strconv.ParseUint(s, 10, 64)
+	case "float32":
+		x, err := strconv.ParseFloat(s, 32)
+		return float32(x), err
+	case "float64":
+		return strconv.ParseFloat(s, 64)
+	}
+	return nil, fmt.Errorf("unsupported json value type %s", typ)
+}
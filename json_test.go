@@ -0,0 +1,93 @@
+package sheet
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	pid := "p_id"
+	listA := "AA"
+	listB := "BB"
+	sample := &SampleUnmarshal{
+		ID:   "id_01",
+		Sub:  &SampleUnmarshalSub{Code: "code_01", Num: 10},
+		Num:  100,
+		Arr:  []string{"A", "B"},
+		PID:  &pid,
+		List: []*string{&listA, &listB},
+		SList: []SampleUnmarshalSub2{
+			{Code: "code_1_01", Num: 1},
+			{Code: "code_1_02", Num: 2},
+		},
+		Now: time.Date(2017, 11, 6, 1, 27, 0, 0, time.Local),
+	}
+	data, err := MarshalJSON(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := &SampleUnmarshal{}
+	if err := UnmarshalJSON(data, actual); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(sample, actual) {
+		t.Errorf("unmarshal %+v, expected %+v", actual, sample)
+	}
+}
+
+func TestDocument(t *testing.T) {
+	doc := &Document{
+		Headers: [][]string{{"a", "b", "c", "d", "e", "f"}},
+		Rows: [][]interface{}{
+			{"text", nil, true, int8(-3), uint64(18446744073709551615), float32(3.1415)},
+			{"", 1, false, int64(9007199254740993), 0.1, nil},
+		},
+	}
+	data, err := doc.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := &Document{}
+	if err := actual.UnmarshalJSON(data); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(doc, actual) {
+		t.Errorf("document %v, expected %v", actual, doc)
+	}
+}
+
+func TestDocumentValue(t *testing.T) {
+	type file struct {
+		Name     string   `json:"name"`
+		Document Document `json:"document"`
+	}
+	f := &file{
+		Name: "sample",
+		Document: Document{
+			Headers: [][]string{{"a", "b"}},
+			Rows:    [][]interface{}{{int8(1), 0.5}},
+		},
+	}
+	data, err := json.Marshal(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := &file{}
+	if err := json.Unmarshal(data, actual); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(f, actual) {
+		t.Errorf("file %+v, expected %+v", actual, f)
+	}
+
+	doc := &Document{}
+	if err := json.Unmarshal([]byte(`{"headers":[["a","b","c"]],"rows":[[1,-2,0.5]]}`), doc); err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]interface{}{{int64(1), int64(-2), 0.5}}
+	if !reflect.DeepEqual(doc.Rows, expected) {
+		t.Errorf("rows %v, expected %v", doc.Rows, expected)
+	}
+}
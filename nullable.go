@@ -0,0 +1,45 @@
+package sheet
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"time"
+)
+
+// isNullable sql.Null*のようにdriver.Valuerとsql.Scannerを実装した型か否か
+func isNullable(t reflect.Type) bool {
+	return lookupTypeInfo(t).nullable
+}
+
+func implementsNullable(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr || t.Kind() == reflect.Interface {
+		return false
+	}
+	pt := reflect.PtrTo(t)
+	return (t.Implements(typeOfValuer) || pt.Implements(typeOfValuer)) && pt.Implements(typeOfScanner)
+}
+
+func nullableValue(v reflect.Value) (driver.Value, error) {
+	if valuer, ok := v.Interface().(driver.Valuer); ok {
+		return valuer.Value()
+	}
+	p := reflect.New(v.Type())
+	p.Elem().Set(v)
+	return p.Interface().(driver.Valuer).Value()
+}
+
+// scanNullable 文字列のScanに失敗した場合は時刻として読み直す
+func scanNullable(v reflect.Value, x interface{}) error {
+	scanner := v.Addr().Interface().(sql.Scanner)
+	err := scanner.Scan(x)
+	if err == nil {
+		return nil
+	}
+	if s, ok := x.(string); ok {
+		if t, terr := time.ParseInLocation(timeFormat, s, time.Local); terr == nil && scanner.Scan(t) == nil {
+			return nil
+		}
+	}
+	return err
+}
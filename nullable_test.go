@@ -0,0 +1,112 @@
+package sheet
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type Optional[T any] struct {
+	V     T
+	Valid bool
+}
+
+func (o Optional[T]) Value() (driver.Value, error) {
+	if !o.Valid {
+		return nil, nil
+	}
+	return o.V, nil
+}
+
+func (o *Optional[T]) Scan(src interface{}) error {
+	*o = Optional[T]{}
+	switch x := src.(type) {
+	case nil:
+		return nil
+	case string:
+		if _, err := fmt.Sscan(x, &o.V); err != nil {
+			return err
+		}
+	default:
+		rv := reflect.ValueOf(src)
+		t := reflect.TypeOf(o.V)
+		if !rv.Type().ConvertibleTo(t) {
+			return fmt.Errorf("cannot scan %T into %s", src, t)
+		}
+		o.V = rv.Convert(t).Interface().(T)
+	}
+	o.Valid = true
+	return nil
+}
+
+type SampleNullable struct {
+	Name    sql.NullString       `sheet:"name"`
+	Num     sql.NullInt64        `sheet:"num"`
+	Time    sql.NullTime         `sheet:"time"`
+	Rate    Optional[float64]    `sheet:"rate"`
+	Nums    []sql.NullInt64      `sheet:"nums"`
+	Created *sql.NullTime        `sheet:"created"`
+	Subs    []SampleNullableItem `sheet:"subs"`
+}
+
+type SampleNullableItem struct {
+	Code sql.NullString `sheet:"code"`
+}
+
+func TestNullable(t *testing.T) {
+	now := time.Date(2017, 11, 6, 1, 27, 0, 0, time.Local)
+	sample := &SampleNullable{
+		Name: sql.NullString{String: "name_01", Valid: true},
+		Time: sql.NullTime{Time: now, Valid: true},
+		Rate: Optional[float64]{V: 0.5, Valid: true},
+		Nums: []sql.NullInt64{{Int64: 1, Valid: true}, {}, {Int64: 3, Valid: true}},
+		Subs: []SampleNullableItem{
+			{Code: sql.NullString{String: "code_01", Valid: true}},
+		},
+	}
+	values, err := Marshal(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]interface{}{
+		{"name_01", nil, "2017-11-06 01:27:00", 0.5, int64(1), nil, 1, "code_01"},
+		{nil, nil, nil, nil, nil, nil, nil, nil},
+		{nil, nil, nil, nil, int64(3), nil, nil, nil},
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("marshal %v, expected %v", values, expected)
+	}
+
+	formats, err := Header(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedFormats := [][]string{
+		{"name", "num", "time", "rate", "nums", "created", "subs", ""},
+		{"", "", "", "", "", "", "_index", "code"},
+	}
+	if !reflect.DeepEqual(formats, expectedFormats) {
+		t.Errorf("header %v, expected %v", formats, expectedFormats)
+	}
+
+	for _, unmarshal := range []func(interface{}) error{
+		func(v interface{}) error {
+			return UnmarshalValues(formats, values, v)
+		},
+		func(v interface{}) error {
+			doc := &Document{Rows: values}
+			return Unmarshal(formats, doc.Values(), v)
+		},
+	} {
+		actual := &SampleNullable{}
+		if err := unmarshal(actual); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(actual, sample) {
+			t.Errorf("unmarshal %+v, expected %+v", actual, sample)
+		}
+	}
+}
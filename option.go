@@ -1,7 +1,10 @@
 package sheet
 
 import (
+	"fmt"
+	"math"
 	"reflect"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -14,19 +17,47 @@ const (
 type option struct {
 	// title タイトル
 	title string
-	// isDatetime int64形式の数字をDatetime形式(2006-01-02 15:04:05)に変換するか否か
+	// isDatetime 整数のエポックをDatetime形式(2006-01-02 15:04:05)に変換するか否か
 	isDatetime bool
+	// unit datetimeのエポックの単位 s, ms, ns
+	unit string
 	// isIndex decode時に基準となるインテックスオプション
 	isIndex bool
 	// isCSV csvオプション、Array or Slice以外では無効
 	isCSV bool
+	// omitEmpty ゼロ値を空のセルにするか否か
+	omitEmpty bool
+	// isFormula 文字列を数式として扱うか否か
+	isFormula bool
+	// boolTrue, boolFalse bool=<true>|<false> で指定した真偽値の表記
+	boolTrue  string
+	boolFalse string
+	// isHorizontal Array or Sliceの要素を列方向に並べるか否か
+	isHorizontal bool
+	// size horizontal=<size>で指定した要素数、Arrayは省略できる
+	size int
+	// aliases alias=<a>|<b>で指定したヘッダーの別名
+	aliases []string
+	// order, hasOrder order=<n>で指定した列の順番
+	order    int
+	hasOrder bool
 }
 
 func (o *option) reset() {
 	o.title = ""
 	o.isDatetime = false
+	o.unit = ""
 	o.isIndex = false
 	o.isCSV = false
+	o.omitEmpty = false
+	o.isFormula = false
+	o.boolTrue = ""
+	o.boolFalse = ""
+	o.isHorizontal = false
+	o.size = 0
+	o.aliases = nil
+	o.order = 0
+	o.hasOrder = false
 }
 
 var optionPool = sync.Pool{
@@ -35,29 +66,189 @@ var optionPool = sync.Pool{
 	},
 }
 
-func newOption(tag string, isTitle bool) *option {
-	tags := strings.Split(tag, ",")
-	opt := optionPool.Get().(*option)
-	for _, tag := range tags {
-		if tag == "datetime" {
-			opt.isDatetime = true
+// Tag sheetタグを解釈した結果
+// タグはname,opt1,opt2,key=valueの形式で、先頭がオプションの場合は名前を省略したものとして扱う
+type Tag struct {
+	// Name 列のキー、省略した場合は空、-の場合はフィールドを出力しない
+	Name string
+	// Options オプションの名前から値、値の無いオプションは空文字
+	Options map[string]string
+}
+
+// ParseTag sheetタグを解釈する、不明なオプションや不正な値はエラーにする
+func ParseTag(tag string) (*Tag, error) {
+	name, tokens := splitTag(tag)
+	t := &Tag{Name: name, Options: map[string]string{}}
+	opt := &option{}
+	for _, token := range tokens {
+		if token == "" {
+			continue
 		}
-		if tag == "index" {
-			opt.isIndex = true
+		if err := opt.parse(token); err != nil {
+			return nil, fmt.Errorf("sheet: tag %q: %w", tag, err)
 		}
-		if tag == "csv" {
-			opt.isCSV = true
+		key, value, _ := strings.Cut(token, "=")
+		t.Options[key] = value
+	}
+	return t, nil
+}
+
+// splitTag 名前とオプションに分ける
+func splitTag(tag string) (string, []string) {
+	tokens := strings.Split(tag, ",")
+	if isOptionToken(tokens[0]) {
+		return "", tokens
+	}
+	return tokens[0], tokens[1:]
+}
+
+// isOptionToken csvやtitle=timeのように名前ではなくオプションの表記か否か
+func isOptionToken(tag string) bool {
+	switch tag {
+	case "datetime", "index", "csv", "omitempty", "formula", "horizontal":
+		return true
+	}
+	return strings.Contains(tag, "=")
+}
+
+// parseTag タグの名前とオプションを返す、オプションはresetOptionで戻す
+func parseTag(tag string) (string, *option, error) {
+	name, tokens := splitTag(tag)
+	opt := optionPool.Get().(*option)
+	for _, token := range tokens {
+		if token == "" {
+			continue
 		}
-		if isTitle && strings.HasPrefix(tag, "title=") {
-			tmp := strings.Split(tag, "=")
-			if len(tmp) > 1 {
-				opt.title = tmp[1]
-			}
+		if err := opt.parse(token); err != nil {
+			resetOption(opt)
+			return "", nil, fmt.Errorf("sheet: tag %q: %w", tag, err)
 		}
 	}
+	return name, opt, nil
+}
+
+// fieldTag フィールドのキーとオプション、名前を省略した場合のキーはフィールド名
+func fieldTag(field reflect.StructField) (string, *option, error) {
+	name, opt, err := parseTag(field.Tag.Get(tagName))
+	if err != nil {
+		return "", nil, fmt.Errorf("sheet: field %s: %w", field.Name, err)
+	}
+	if name == "" {
+		name = field.Name
+	}
+	return name, opt, nil
+}
+
+// newOption ヘッダーのキーの:以降のオプションを解釈する、不明なオプションは無視する
+func newOption(tag string) *option {
+	opt := optionPool.Get().(*option)
+	for _, token := range strings.Split(tag, ",") {
+		_ = opt.parse(token)
+	}
 	return opt
 }
 
+// parse オプションを1つ解釈する
+func (o *option) parse(token string) error {
+	key, value, hasValue := strings.Cut(token, "=")
+	if !hasValue {
+		switch token {
+		case "":
+		case "datetime":
+			o.isDatetime = true
+		case "index":
+			o.isIndex = true
+		case "csv":
+			o.isCSV = true
+		case "omitempty":
+			o.omitEmpty = true
+		case "formula":
+			o.isFormula = true
+		case "horizontal":
+			o.isHorizontal = true
+		default:
+			return fmt.Errorf("unknown option %q", token)
+		}
+		return nil
+	}
+	switch key {
+	case "unit":
+		switch value {
+		case "s", "ms", "ns":
+			o.unit = value
+		default:
+			return fmt.Errorf("invalid unit %q", value)
+		}
+	case "bool":
+		tmp := strings.SplitN(value, "|", 2)
+		if len(tmp) != 2 || tmp[0] == "" || tmp[1] == "" {
+			return fmt.Errorf("invalid bool %q, expected <true>|<false>", value)
+		}
+		o.boolTrue, o.boolFalse = tmp[0], tmp[1]
+	case "horizontal":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return fmt.Errorf("invalid horizontal size %q", value)
+		}
+		o.isHorizontal = true
+		o.size = n
+	case "order":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid order %q", value)
+		}
+		o.order = n
+		o.hasOrder = true
+	case "title":
+		o.title = value
+	case "alias":
+		o.aliases = strings.Split(value, "|")
+	default:
+		return fmt.Errorf("unknown option %q", token)
+	}
+	return nil
+}
+
+// format decoderが読むヘッダーのオプションの表記
+func (o *option) format() string {
+	var opts []string
+	if o.isDatetime {
+		opts = append(opts, "datetime")
+	}
+	if o.isCSV {
+		opts = append(opts, "csv")
+	}
+	if o.unit != "" {
+		opts = append(opts, "unit="+o.unit)
+	}
+	if o.boolTrue != "" {
+		opts = append(opts, "bool="+o.boolTrue+"|"+o.boolFalse)
+	}
+	if o.isHorizontal {
+		opts = append(opts, "horizontal")
+	}
+	return strings.Join(opts, ",")
+}
+
+// elem horizontalの要素に渡すオプション
+func (o *option) elem() *option {
+	elem := *o
+	elem.isHorizontal = false
+	elem.size = 0
+	return &elem
+}
+
+// horizontalSize 列方向に並べる要素数
+func horizontalSize(t reflect.Type, opt *option) (int, error) {
+	if opt.size > 0 {
+		return opt.size, nil
+	}
+	if t.Kind() == reflect.Array {
+		return t.Len(), nil
+	}
+	return 0, fmt.Errorf("sheet: horizontal slice %s needs horizontal=<size>", t)
+}
+
 func resetOption(opt *option) {
 	if opt != nil {
 		opt.reset()
@@ -65,20 +256,68 @@ func resetOption(opt *option) {
 	}
 }
 
-func encodeDatetime(v reflect.Value) (interface{}, error) {
+// datetimeLayout ms, nsの場合は小数点以下の秒も出力する
+func datetimeLayout(opt *option) string {
+	if opt != nil {
+		switch opt.unit {
+		case "ms":
+			return timeFormat + ".000"
+		case "ns":
+			return timeFormat + ".000000000"
+		}
+	}
+	return timeFormat
+}
+
+func fromUnix(n int64, opt *option) time.Time {
+	if opt != nil {
+		switch opt.unit {
+		case "ms":
+			return time.Unix(n/1e3, n%1e3*1e6)
+		case "ns":
+			return time.Unix(0, n)
+		}
+	}
+	return time.Unix(n, 0)
+}
+
+func toUnix(t time.Time, opt *option) int64 {
+	if opt != nil {
+		switch opt.unit {
+		case "ms":
+			return t.UnixNano() / 1e6
+		case "ns":
+			return t.UnixNano()
+		}
+	}
+	return t.Unix()
+}
+
+// encodeDatetime 時刻として出力する値、文字列にするのはGridの外に出す時に限る
+func encodeDatetime(v reflect.Value, opt *option) (interface{}, error) {
 	if v.Type() == typeOfTime {
 		t := v.Interface().(time.Time)
 		if t.IsZero() {
 			return nil, nil
 		}
-		return t.Format(timeFormat), nil
+		return t, nil
 	}
-	if v.Kind() == reflect.Int64 {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		t := v.Int()
 		if t <= 0 {
 			return nil, nil
 		}
-		return time.Unix(t, 0).Format(timeFormat), nil
+		return fromUnix(t, opt), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		t := v.Uint()
+		if t == 0 {
+			return nil, nil
+		}
+		if t > math.MaxInt64 {
+			return nil, fmt.Errorf("value %d overflows datetime", t)
+		}
+		return fromUnix(int64(t), opt), nil
 	}
 	return v.Interface(), nil
 }
@@ -0,0 +1,58 @@
+package sheet
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTag(t *testing.T) {
+	for tag, expected := range map[string]*Tag{
+		"":                       {Name: "", Options: map[string]string{}},
+		"id":                     {Name: "id", Options: map[string]string{}},
+		"id,index":               {Name: "id", Options: map[string]string{"index": ""}},
+		",csv":                   {Name: "", Options: map[string]string{"csv": ""}},
+		"csv":                    {Name: "", Options: map[string]string{"csv": ""}},
+		"datetime,title=time":    {Name: "", Options: map[string]string{"datetime": "", "title": "time"}},
+		"at,datetime,unit=ms":    {Name: "at", Options: map[string]string{"datetime": "", "unit": "ms"}},
+		"ok,bool=○|×":            {Name: "ok", Options: map[string]string{"bool": "○|×"}},
+		"list,horizontal=3":      {Name: "list", Options: map[string]string{"horizontal": "3"}},
+		"name,alias=a|b,order=2": {Name: "name", Options: map[string]string{"alias": "a|b", "order": "2"}},
+	} {
+		actual, err := ParseTag(tag)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("parse %q %+v, expected %+v", tag, actual, expected)
+		}
+	}
+
+	for _, tag := range []string{
+		"id,foo",
+		"id,unit=h",
+		"id,bool=yes",
+		"id,horizontal=0",
+		"id,order=first",
+		"foo=bar",
+	} {
+		if _, err := ParseTag(tag); err == nil {
+			t.Errorf("parse %q should fail", tag)
+		}
+	}
+}
+
+type SampleInvalidTag struct {
+	ID string `sheet:"id,csvv"`
+}
+
+func TestInvalidTag(t *testing.T) {
+	if _, err := Marshal(&SampleInvalidTag{}); err == nil {
+		t.Error("marshal with unknown option should fail")
+	}
+	if _, err := Header(&SampleInvalidTag{}); err == nil {
+		t.Error("header with unknown option should fail")
+	}
+	if err := Unmarshal([][]string{{"id"}}, [][]string{{"id_01"}}, &SampleInvalidTag{}); err == nil {
+		t.Error("unmarshal with unknown option should fail")
+	}
+}
@@ -0,0 +1,173 @@
+package sheet
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// WithHeaderCell ヘッダーの左上のセルをB3のように指定する、UnmarshalSheetで使う
+func WithHeaderCell(cell string) Option {
+	return func(c *config) {
+		c.headerCell = cell
+	}
+}
+
+// WithHeaderDetection 構造体のキーと一致するセルがある最初の行をヘッダーとする、UnmarshalSheetで使う
+func WithHeaderDetection() Option {
+	return func(c *config) {
+		c.detectHeader = true
+	}
+}
+
+// WithCommentPrefix 先頭のセルがprefixで始まる行を読み飛ばす
+func WithCommentPrefix(prefix string) Option {
+	return func(c *config) {
+		c.comment = prefix
+	}
+}
+
+// WithCommentColumn ヘッダーのいずれかの行がmarkersで始まる列をメモ欄として読み飛ばす
+func WithCommentColumn(markers ...string) Option {
+	return func(c *config) {
+		c.commentColumns = markers
+	}
+}
+
+// isCommentColumn コメントの列のヘッダーか否か
+func (c *config) isCommentColumn(format string) bool {
+	for _, marker := range c.commentColumns {
+		if marker != "" && strings.HasPrefix(format, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// isComment 先頭のセルがコメントの行か否か
+func (c *config) isComment(row []string) bool {
+	return c.comment != "" && len(row) > 0 && strings.HasPrefix(row[0], c.comment)
+}
+
+// UnmarshalSheet ヘッダーと値を含むシート全体からdecodeする
+// ヘッダーの位置はWithHeaderCell、WithHeaderDetectionで指定し、無ければA1から始まるとする
+// ヘッダーの行数はHeaderと同じく型から決める
+func UnmarshalSheet(sheet [][]string, v interface{}, opts ...Option) error {
+	hdr := newHeaderEncoder(opts...)
+	transposed := hdr.transpose
+	hdr.transpose = false
+	header, err := hdr.Encode(v)
+	if err != nil {
+		return err
+	}
+	if transposed {
+		sheet = transpose(sheet)
+		opts = append(opts, func(c *config) {
+			c.transpose = false
+		})
+	}
+
+	row, column, err := findHeader(sheet, header, hdr.config, transposed)
+	if err != nil {
+		return err
+	}
+	formats := make([][]string, 0, len(header))
+	values := make([][]string, 0, len(sheet))
+	// rows 値の行ごとのシートの行
+	rows := make([]int, 0, len(sheet))
+	for i := row; i < len(sheet); i++ {
+		if hdr.isComment(sheet[i]) {
+			continue
+		}
+		cells := []string{}
+		if column < len(sheet[i]) {
+			cells = sheet[i][column:]
+		}
+		if len(formats) < len(header) {
+			formats = append(formats, cells)
+		} else {
+			values = append(values, cells)
+			rows = append(rows, i)
+		}
+	}
+	err = Unmarshal(formats, values, v, opts...)
+	// CellErrorはシート上の位置にする
+	var e *CellError
+	if errors.As(err, &e) {
+		if e.Row < len(rows) {
+			e.Row = rows[e.Row]
+		}
+		e.Column += column
+		if transposed {
+			e.Row, e.Column = e.Column, e.Row
+		}
+	}
+	return err
+}
+
+// findHeader ヘッダーの左上のセルの位置
+// WithHeaderCellのセルは入れ替える前のシートの位置として扱う
+func findHeader(sheet [][]string, header [][]string, c *config, transposed bool) (int, int, error) {
+	keys := map[string]bool{}
+	if len(header) > 0 {
+		for _, key := range header[0] {
+			if key != "" {
+				keys[strings.SplitN(key, ":", 2)[0]] = true
+			}
+		}
+	}
+	isKey := func(cell string) bool {
+		return keys[strings.SplitN(cell, ":", 2)[0]]
+	}
+
+	if c.headerCell != "" {
+		row, column, err := parseCell(c.headerCell)
+		if err != nil {
+			return 0, 0, err
+		}
+		if transposed {
+			row, column = column, row
+		}
+		if row < len(sheet) && column < len(sheet[row]) {
+			for _, cell := range sheet[row][column:] {
+				if isKey(cell) {
+					return row, column, nil
+				}
+			}
+		}
+		return 0, 0, fmt.Errorf("sheet: header not found at %s", c.headerCell)
+	}
+	if !c.detectHeader || len(header) == 0 {
+		return 0, 0, nil
+	}
+	for i, cells := range sheet {
+		if c.isComment(cells) {
+			continue
+		}
+		for j, cell := range cells {
+			if isKey(cell) {
+				return i, j, nil
+			}
+		}
+	}
+	return 0, 0, fmt.Errorf("sheet: header not found")
+}
+
+// parseCell B3のようなセルの位置を0から始まる行と列にする
+func parseCell(cell string) (int, int, error) {
+	column, i := 0, 0
+	for ; i < len(cell) && 'A' <= cell[i]&^0x20 && cell[i]&^0x20 <= 'Z'; i++ {
+		column = column*26 + int(cell[i]&^0x20-'A') + 1
+	}
+	row := 0
+	for _, r := range cell[i:] {
+		if r < '0' || r > '9' {
+			return 0, 0, fmt.Errorf("sheet: invalid cell %q", cell)
+		}
+		row = row*10 + int(r-'0')
+	}
+	if column == 0 || row == 0 {
+		return 0, 0, fmt.Errorf("sheet: invalid cell %q", cell)
+	}
+	return row - 1, column - 1, nil
+}
@@ -0,0 +1,147 @@
+package sheet
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestUnmarshalSheet(t *testing.T) {
+	sheet := [][]string{
+		{"Stream master"},
+		{"# generated", "", "", "", ""},
+		{"", "id", "num", "hoges", "", ""},
+		{"", "", "", "_index", "Title", "Order"},
+		{"", "id_01", "1", "1", "title_1_1", "1"},
+		{"# note", "", "", "", "comment", ""},
+		{"", "", "", "2", "title_1_2", "2"},
+	}
+	expected := &SampleStream{
+		ID:  "id_01",
+		Num: 1,
+		Hoges: []SampleHoge{
+			{Title: "title_1_1", Order: 1},
+			{Title: "title_1_2", Order: 2},
+		},
+	}
+	for _, opt := range []Option{WithHeaderCell("B3"), WithHeaderDetection()} {
+		actual := &SampleStream{}
+		if err := UnmarshalSheet(sheet, actual, opt, WithCommentPrefix("#")); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("unmarshal %+v, expected %+v", actual, expected)
+		}
+	}
+
+	if err := UnmarshalSheet(sheet, &SampleStream{}, WithHeaderCell("B4")); err == nil {
+		t.Error("unmarshal without keys at header cell should fail")
+	}
+	if err := UnmarshalSheet(sheet, &SampleStream{}, WithHeaderCell("3B")); err == nil {
+		t.Error("unmarshal with invalid cell should fail")
+	}
+	if err := UnmarshalSheet(sheet[:2], &SampleStream{}, WithHeaderDetection()); err == nil {
+		t.Error("unmarshal without header should fail")
+	}
+}
+
+type SampleSettings struct {
+	Name string `sheet:"name"`
+	Port int    `sheet:"port"`
+}
+
+func TestUnmarshalSheetTranspose(t *testing.T) {
+	// キーがB3から縦に並ぶ設定のシート
+	sheet := [][]string{
+		{"Settings"},
+		{},
+		{"", "name", "server"},
+		{"", "port", "8080"},
+	}
+	actual := &SampleSettings{}
+	if err := UnmarshalSheet(sheet, actual, WithTranspose(), WithHeaderCell("B3")); err != nil {
+		t.Fatal(err)
+	}
+	if actual.Name != "server" || actual.Port != 8080 {
+		t.Errorf("unmarshal %+v", actual)
+	}
+	if err := UnmarshalSheet(sheet, &SampleSettings{}, WithTranspose(), WithHeaderCell("C2")); err == nil {
+		t.Error("unmarshal with transposed header cell should fail")
+	}
+}
+
+func TestUnmarshalSheetCellError(t *testing.T) {
+	sheet := [][]string{
+		{"Stream master"},
+		{"", "id", "num", "hoges", "", ""},
+		{"", "", "", "_index", "Title", "Order"},
+		{"", "id_01", "1", "1", "title_1_1", "1"},
+		{"# note"},
+		{"", "", "", "2", "title_1_2", "x"},
+	}
+	transposed := [][]string{
+		{"Settings"},
+		{"# note"},
+		{"", "name", "server"},
+		{"", "port", "x"},
+	}
+	for _, c := range []struct {
+		sheet  [][]string
+		v      interface{}
+		opts   []Option
+		row    int
+		column int
+	}{
+		{sheet: sheet, v: &SampleStream{}, opts: []Option{WithHeaderCell("B2")}, row: 5, column: 5},
+		{sheet: transposed, v: &SampleSettings{}, opts: []Option{WithTranspose(), WithHeaderCell("B3")}, row: 3, column: 2},
+	} {
+		err := UnmarshalSheet(c.sheet, c.v, append(c.opts, WithCommentPrefix("#"))...)
+		var cellErr *CellError
+		if !errors.As(err, &cellErr) || cellErr.Row != c.row || cellErr.Column != c.column {
+			t.Errorf("unmarshal error %v, expected CellError at row %d, column %d", err, c.row, c.column)
+		}
+	}
+
+	// コメントの行を除く前の値の位置
+	formats := [][]string{{"int8", "uint16"}}
+	values := [][]string{{"# note"}, {"# note"}, {"1", "x"}}
+	for _, c := range []struct {
+		formats [][]string
+		values  [][]string
+		opts    []Option
+		row     int
+		column  int
+	}{
+		{formats: formats, values: values, row: 2, column: 1},
+		{formats: transpose(formats), values: transpose(values), opts: []Option{WithTranspose()}, row: 1, column: 2},
+	} {
+		err := Unmarshal(c.formats, c.values, &SampleNumbers{}, append(c.opts, WithCommentPrefix("#"))...)
+		var cellErr *CellError
+		if !errors.As(err, &cellErr) || cellErr.Row != c.row || cellErr.Column != c.column {
+			t.Errorf("unmarshal error %v, expected CellError at row %d, column %d", err, c.row, c.column)
+		}
+	}
+}
+
+func TestParseCell(t *testing.T) {
+	for cell, expected := range map[string][2]int{
+		"A1":   {0, 0},
+		"B3":   {2, 1},
+		"z10":  {9, 25},
+		"AA2":  {1, 26},
+		"AB12": {11, 27},
+	} {
+		row, column, err := parseCell(cell)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if row != expected[0] || column != expected[1] {
+			t.Errorf("parse %s (%d, %d), expected %v", cell, row, column, expected)
+		}
+	}
+	for _, cell := range []string{"", "A", "1", "A0", "A-1"} {
+		if _, _, err := parseCell(cell); err == nil {
+			t.Errorf("parse %q should fail", cell)
+		}
+	}
+}
@@ -1,8 +1,16 @@
 package sheet
 
 import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
 	"reflect"
+	"sort"
+	"strconv"
+	"sync"
 	"time"
+	"unicode"
 )
 
 const (
@@ -10,17 +18,252 @@ const (
 )
 
 var (
-	typeOfTime = reflect.TypeOf(time.Time{})
+	typeOfTime    = reflect.TypeOf(time.Time{})
+	typeOfValuer  = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+	typeOfScanner = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
 )
 
-func Marshal(v interface{}) ([][]interface{}, error) {
-	return newEncoder().Encode(v)
+func Marshal(v interface{}, opts ...Option) ([][]interface{}, error) {
+	return newEncoder(opts...).Encode(v)
 }
 
-func Unmarshal(formats [][]string, values [][]string, v interface{}) error {
-	return newDecoder(formats).Decode(values, v)
+// MarshalGrid Marshalと同じ配置で値の種類を持ったGridを返す
+func MarshalGrid(v interface{}, opts ...Option) (*Grid, error) {
+	return newEncoder(opts...).EncodeGrid(v)
 }
 
-func Header(v interface{}) {
-	newHeaderEncoder().Encode(v)
+func Unmarshal(formats [][]string, values [][]string, v interface{}, opts ...Option) error {
+	return newDecoder(formats, opts...).Decode(values, v)
+}
+
+// UnmarshalValues 数値や真偽値、time.Timeを文字列を経由せずに代入する
+func UnmarshalValues(formats [][]string, values [][]interface{}, v interface{}, opts ...Option) error {
+	return newDecoder(formats, opts...).DecodeValues(values, v)
+}
+
+func UnmarshalGrid(formats [][]string, grid *Grid, v interface{}, opts ...Option) error {
+	return newDecoder(formats, opts...).DecodeGrid(grid, v)
+}
+
+func Header(v interface{}, opts ...Option) ([][]string, error) {
+	return newHeaderEncoder(opts...).Encode(v)
+}
+
+func MarshalJSON(v interface{}, opts ...Option) ([]byte, error) {
+	headers, err := Header(v, opts...)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := Marshal(v, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(&Document{Headers: headers, Rows: rows}, "", "  ")
+}
+
+func UnmarshalJSON(data []byte, v interface{}, opts ...Option) error {
+	doc := &Document{}
+	if err := json.Unmarshal(data, doc); err != nil {
+		return err
+	}
+	return UnmarshalValues(doc.Headers, doc.Rows, v, opts...)
+}
+
+// fieldKey tagの先頭の名前、無い場合や先頭がオプションの場合はフィールド名
+func fieldKey(field reflect.StructField) string {
+	if name, _ := splitTag(field.Tag.Get(tagName)); name != "" {
+		return name
+	}
+	return field.Name
+}
+
+// structField 列に並べるフィールドと解釈済みのタグ、optは共有するのでresetOptionしない
+type structField struct {
+	index int
+	key   string
+	opt   *option
+}
+
+// fieldsKey 型とWithColumnOrderの組ごとにフィールドの並びをキャッシュする
+type fieldsKey struct {
+	typ   reflect.Type
+	order string
+}
+
+var fieldsCache sync.Map // fieldsKey -> []structField
+
+// typeFields fieldOrderの順に並べたフィールドとタグ、型とWithColumnOrderごとに1度だけ解釈する
+func typeFields(t reflect.Type, c *config) ([]structField, error) {
+	k := fieldsKey{typ: t, order: c.columnOrderKey}
+	if x, ok := fieldsCache.Load(k); ok {
+		return x.([]structField), nil
+	}
+	indexes := fieldOrder(t, c)
+	fields := make([]structField, 0, len(indexes))
+	for _, i := range indexes {
+		key, opt, err := fieldTag(t.Field(i))
+		if err != nil {
+			for _, f := range fields {
+				resetOption(f.opt)
+			}
+			return nil, err
+		}
+		fields = append(fields, structField{index: i, key: key, opt: opt})
+	}
+	x, _ := fieldsCache.LoadOrStore(k, fields)
+	return x.([]structField), nil
+}
+
+// fieldOrder 列に並べるフィールドのインデックス
+// WithColumnOrderのキー、order=の昇順、宣言順の順に並べる
+func fieldOrder(t reflect.Type, c *config) []int {
+	type rank struct {
+		index int
+		group int
+		order int
+	}
+	ranks := make([]rank, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !unicode.IsUpper(rune(field.Name[0])) {
+			continue
+		}
+		tag := field.Tag.Get(tagName)
+		if tag == "-" {
+			continue
+		}
+		r := rank{index: i, group: 2}
+		if n, ok := c.columnOrder[fieldKey(field)]; ok {
+			r.group, r.order = 0, n
+		} else if _, opt, err := parseTag(tag); err == nil {
+			// 不正なタグはencoderがエラーにする
+			if opt.hasOrder {
+				r.group, r.order = 1, opt.order
+			}
+			resetOption(opt)
+		}
+		ranks = append(ranks, r)
+	}
+	sort.SliceStable(ranks, func(i, j int) bool {
+		if ranks[i].group != ranks[j].group {
+			return ranks[i].group < ranks[j].group
+		}
+		return ranks[i].order < ranks[j].order
+	})
+	indexes := make([]int, len(ranks))
+	for i := range ranks {
+		indexes[i] = ranks[i].index
+	}
+	return indexes
+}
+
+// typeInfo 値ごとに判定すると遅いため型ごとにキャッシュする判定
+type typeInfo struct {
+	leaf     bool
+	nullable bool
+}
+
+var typeInfos sync.Map // reflect.Type -> typeInfo
+
+func lookupTypeInfo(t reflect.Type) typeInfo {
+	if x, ok := typeInfos.Load(t); ok {
+		return x.(typeInfo)
+	}
+	info := typeInfo{nullable: implementsNullable(t)}
+	info.leaf = info.nullable || checkLeaf(t)
+	typeInfos.Store(t, info)
+	return info
+}
+
+// resetTypeInfos enumやunionの登録で変わる判定を捨てる
+func resetTypeInfos() {
+	typeInfos.Range(func(k, _ interface{}) bool {
+		typeInfos.Delete(k)
+		return true
+	})
+}
+
+// isLeaf 1つのセルに出力される型か否か
+func isLeaf(t reflect.Type) bool {
+	return lookupTypeInfo(t).leaf
+}
+
+func checkLeaf(t reflect.Type) bool {
+	if t == typeOfTime || lookupEnum(t) != nil {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Struct, reflect.Array, reflect.Slice:
+		return false
+	case reflect.Interface:
+		return lookupUnion(t) == nil
+	}
+	return true
+}
+
+// layoutKind time.Timeなど1つのセルに収まる構造体はreflect.Invalidとして扱う
+func layoutKind(t reflect.Type) reflect.Kind {
+	if t.Kind() == reflect.Struct && isLeaf(t) {
+		return reflect.Invalid
+	}
+	return t.Kind()
+}
+
+// formatValue Marshalの値をUnmarshalで読める文字列にする
+func formatValue(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case bool:
+		return strconv.FormatBool(x)
+	case int:
+		return strconv.FormatInt(int64(x), 10)
+	case int8:
+		return strconv.FormatInt(int64(x), 10)
+	case int16:
+		return strconv.FormatInt(int64(x), 10)
+	case int32:
+		return strconv.FormatInt(int64(x), 10)
+	case int64:
+		return strconv.FormatInt(x, 10)
+	case uint:
+		return strconv.FormatUint(uint64(x), 10)
+	case uint8:
+		return strconv.FormatUint(uint64(x), 10)
+	case uint16:
+		return strconv.FormatUint(uint64(x), 10)
+	case uint32:
+		return strconv.FormatUint(uint64(x), 10)
+	case uint64:
+		return strconv.FormatUint(x, 10)
+	case float32:
+		return strconv.FormatFloat(float64(x), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case time.Time:
+		return x.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(v)
+}
+
+// transpose 行と列を入れ替える、足りないセルはゼロ値で埋める
+func transpose[T any](rows [][]T) [][]T {
+	n := 0
+	for i := range rows {
+		if n < len(rows[i]) {
+			n = len(rows[i])
+		}
+	}
+	ret := make([][]T, n)
+	for i := range ret {
+		ret[i] = make([]T, len(rows))
+		for j := range rows {
+			if i < len(rows[j]) {
+				ret[i][j] = rows[j][i]
+			}
+		}
+	}
+	return ret
 }
@@ -1,7 +1,10 @@
 package sheet
 
 import (
+	"io"
+	"reflect"
 	"testing"
+	"time"
 )
 
 type Integer struct {
@@ -46,3 +49,390 @@ func TestMarshal(t *testing.T) {
 func Test_Unmarshal(t *testing.T) {
 
 }
+
+type SampleBool struct {
+	Active bool   `sheet:"active,bool=○|×"`
+	Public bool   `sheet:"public"`
+	Flags  []bool `sheet:"flags,csv"`
+}
+
+func TestBoolVocabulary(t *testing.T) {
+	sample := &SampleBool{Active: true, Flags: []bool{true, false}}
+	opt := WithBoolVocabulary([]string{"TRUE", "yes", "ON"}, []string{"FALSE", "no", "OFF"})
+	values, err := Marshal(sample, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]interface{}{{"○", "FALSE", "TRUE,FALSE"}}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("marshal %v, expected %v", values, expected)
+	}
+
+	formats, err := Header(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := &SampleBool{}
+	if err := Unmarshal(formats, [][]string{{"○", "off", "yes,no"}}, actual, opt); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(actual, sample) {
+		t.Errorf("unmarshal %+v, expected %+v", actual, sample)
+	}
+
+	for _, row := range [][]string{{"yes"}, {"", "1"}} {
+		if err := Unmarshal(formats, [][]string{row}, &SampleBool{}, opt); err == nil {
+			t.Errorf("unmarshal %v should fail", row)
+		}
+	}
+}
+
+type SampleDatetime struct {
+	Int32  int32  `sheet:"int32,datetime"`
+	Uint32 uint32 `sheet:"uint32,datetime"`
+	Milli  int64  `sheet:"milli,datetime,unit=ms"`
+	Nano   uint64 `sheet:"nano,datetime,unit=ns"`
+}
+
+func TestDatetimeUnit(t *testing.T) {
+	now := time.Date(2017, 11, 6, 1, 27, 0, 123456789, time.Local)
+	sample := &SampleDatetime{
+		Int32:  int32(now.Unix()),
+		Uint32: uint32(now.Unix()),
+		Milli:  now.UnixNano() / 1e6,
+		Nano:   uint64(now.UnixNano()),
+	}
+	values, err := Marshal(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]interface{}{{
+		"2017-11-06 01:27:00",
+		"2017-11-06 01:27:00",
+		"2017-11-06 01:27:00.123",
+		"2017-11-06 01:27:00.123456789",
+	}}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("marshal %v, expected %v", values, expected)
+	}
+
+	formats, err := Header(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := &SampleDatetime{}
+	if err := UnmarshalValues(formats, values, actual); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(actual, sample) {
+		t.Errorf("unmarshal %+v, expected %+v", actual, sample)
+	}
+}
+
+type SampleNull struct {
+	ID    string   `sheet:"id"`
+	Num   int      `sheet:"num"`
+	Count int      `sheet:"count,omitempty"`
+	PNum  *int     `sheet:"pnum"`
+	List  []string `sheet:"list"`
+}
+
+func TestNullPolicy(t *testing.T) {
+	sample := &SampleNull{ID: "id_01"}
+	values, err := Marshal(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]interface{}{{"id_01", 0, nil, nil, nil}}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("marshal %v, expected %v", values, expected)
+	}
+
+	policy := WithNullPolicy(NullPolicy{
+		Zero:  BlankCell,
+		Nil:   BlankLiteral,
+		Empty: BlankZero,
+	})
+	values, err = Marshal(sample, policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = [][]interface{}{{"id_01", nil, nil, "NULL", ""}}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("marshal %v, expected %v", values, expected)
+	}
+
+	formats, err := Header(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual := &SampleNull{}
+	if err := Unmarshal(formats, [][]string{{"id_01", "", "", "NULL"}}, actual, policy); err != nil {
+		t.Fatal(err)
+	}
+	if actual.PNum != nil {
+		t.Errorf("unmarshal pnum %v, expected nil", *actual.PNum)
+	}
+	if err := Unmarshal(formats, [][]string{{"id_01", "", "", "0"}}, actual, policy); err != nil {
+		t.Fatal(err)
+	}
+	if actual.PNum == nil || *actual.PNum != 0 {
+		t.Errorf("unmarshal pnum %v, expected 0", actual.PNum)
+	}
+}
+
+type SampleNode struct {
+	Name   string      `sheet:"name"`
+	Parent *SampleNode `sheet:"parent"`
+}
+
+type SamplePointerRoot struct {
+	A *SamplePointerA `sheet:"a"`
+}
+
+type SamplePointerA struct {
+	B SamplePointerB  `sheet:"b"`
+	P *SamplePointerB `sheet:"p"`
+}
+
+type SamplePointerB struct {
+	Name string `sheet:"name"`
+}
+
+func TestRecursiveType(t *testing.T) {
+	node := &SampleNode{Name: "child", Parent: &SampleNode{Name: "root"}}
+	if _, err := Marshal(node); err == nil {
+		t.Error("marshal recursive type should fail")
+	}
+	if _, err := Header(node); err == nil {
+		t.Error("header recursive type should fail")
+	}
+
+	values, err := Marshal(node, WithMaxDepth(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]interface{}{{"child", "root", nil, nil}}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("marshal %v, expected %v", values, expected)
+	}
+	formats, err := Header(node, WithMaxDepth(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedFormats := [][]string{
+		{"name", "parent", "", ""},
+		{"", "name", "parent", ""},
+		{"", "", "name", "parent"},
+	}
+	if !reflect.DeepEqual(formats, expectedFormats) {
+		t.Errorf("header %v, expected %v", formats, expectedFormats)
+	}
+
+	actual := &SampleNode{}
+	if err := Unmarshal(formats, [][]string{{"child", "root"}}, actual); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(actual, node) {
+		t.Errorf("unmarshal %+v, expected %+v", actual, node)
+	}
+
+	deep := &SampleNode{Parent: &SampleNode{Parent: &SampleNode{Parent: &SampleNode{}}}}
+	if _, err := Marshal(deep, WithMaxDepth(2)); err == nil {
+		t.Error("marshal deeper than max depth should fail")
+	}
+	cyclic := &SampleNode{Name: "a"}
+	cyclic.Parent = cyclic
+	if _, err := Marshal(cyclic, WithMaxDepth(100)); err == nil {
+		t.Error("marshal cyclic pointer should fail")
+	}
+
+	// 構造体と先頭のフィールドは同じアドレスでも循環ではない
+	a := &SamplePointerA{B: SamplePointerB{Name: "b"}}
+	a.P = &a.B
+	values, err = Marshal(&SamplePointerRoot{A: a})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = [][]interface{}{{"b", "b"}}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("marshal %v, expected %v", values, expected)
+	}
+}
+
+type SampleHorizontal struct {
+	ID      string        `sheet:"id"`
+	Rewards [2]SampleHoge `sheet:"rewards,horizontal"`
+	Tags    []string      `sheet:"tags,horizontal=3"`
+	Num     int           `sheet:"num"`
+}
+
+func TestHorizontal(t *testing.T) {
+	sample := &SampleHorizontal{
+		ID:      "id_01",
+		Rewards: [2]SampleHoge{{Title: "title_01", Order: 1}, {Title: "title_02", Order: 2}},
+		Tags:    []string{"A", "B"},
+		Num:     10,
+	}
+	formats, err := Header(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedFormats := [][]string{
+		{"id", "rewards:horizontal", "", "", "", "tags:horizontal", "", "", "num"},
+		{"", "1", "", "2", "", "1", "2", "3", ""},
+		{"", "Title", "Order", "Title", "Order", "", "", "", ""},
+	}
+	if !reflect.DeepEqual(formats, expectedFormats) {
+		t.Errorf("header %v, expected %v", formats, expectedFormats)
+	}
+	values, err := Marshal(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]interface{}{{"id_01", "title_01", 1, "title_02", 2, "A", "B", nil, 10}}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("marshal %v, expected %v", values, expected)
+	}
+
+	actual := &SampleHorizontal{}
+	if err := UnmarshalValues(formats, values, actual); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(actual, sample) {
+		t.Errorf("unmarshal %+v, expected %+v", actual, sample)
+	}
+
+	sample.Tags = []string{"A", "B", "C", "D"}
+	if _, err := Marshal(sample); err == nil {
+		t.Error("marshal more elements than horizontal size should fail")
+	}
+}
+
+type SampleConfig struct {
+	Name string     `sheet:"name"`
+	Port int        `sheet:"port"`
+	Tags []string   `sheet:"tags"`
+	Hoge SampleHoge `sheet:"hoge"`
+}
+
+func TestTranspose(t *testing.T) {
+	sample := &SampleConfig{
+		Name: "server",
+		Port: 8080,
+		Tags: []string{"A", "B"},
+		Hoge: SampleHoge{Title: "title", Order: 1},
+	}
+	formats, err := Header(sample, WithTranspose())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedFormats := [][]string{
+		{"name", ""},
+		{"port", ""},
+		{"tags", ""},
+		{"hoge", "Title"},
+		{"", "Order"},
+	}
+	if !reflect.DeepEqual(formats, expectedFormats) {
+		t.Errorf("header %v, expected %v", formats, expectedFormats)
+	}
+	values, err := Marshal(sample, WithTranspose())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]interface{}{
+		{"server", nil},
+		{8080, nil},
+		{"A", "B"},
+		{"title", nil},
+		{1, nil},
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("marshal %v, expected %v", values, expected)
+	}
+	grid, err := MarshalGrid(sample, WithTranspose())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if grid.Rows() != 5 || grid.Cell(2, 1).Value != "B" {
+		t.Errorf("marshal grid %v", grid.Values())
+	}
+
+	actual := &SampleConfig{}
+	if err := UnmarshalValues(formats, values, actual, WithTranspose()); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(actual, sample) {
+		t.Errorf("unmarshal %+v, expected %+v", actual, sample)
+	}
+
+	// キーがA列、値がB列のシート
+	actual = &SampleConfig{}
+	formats = [][]string{{"name"}, {"port"}}
+	if err := Unmarshal(formats, [][]string{{"server"}, {"8080"}}, actual, WithTranspose()); err != nil {
+		t.Fatal(err)
+	}
+	if actual.Name != "server" || actual.Port != 8080 {
+		t.Errorf("unmarshal %+v", actual)
+	}
+
+	enc := NewStreamEncoder(NewCSVWriter(io.Discard), WithTranspose())
+	if err := enc.Encode(sample); err == nil {
+		t.Error("stream encode with transpose should fail")
+	}
+}
+
+type SampleOrder struct {
+	Num  int        `sheet:"num,order=2"`
+	Memo string     `sheet:"memo"`
+	ID   string     `sheet:"id,order=1"`
+	Hoge SampleHoge `sheet:"hoge"`
+}
+
+func TestColumnOrder(t *testing.T) {
+	sample := &SampleOrder{Num: 1, Memo: "memo", ID: "id_01", Hoge: SampleHoge{Title: "title", Order: 2}}
+	for _, c := range []struct {
+		opts    []Option
+		formats [][]string
+		values  [][]interface{}
+	}{
+		{
+			formats: [][]string{
+				{"id", "num", "memo", "hoge", ""},
+				{"", "", "", "Title", "Order"},
+			},
+			values: [][]interface{}{{"id_01", 1, "memo", "title", 2}},
+		},
+		{
+			opts: []Option{WithColumnOrder("hoge", "Order", "memo")},
+			formats: [][]string{
+				{"hoge", "", "memo", "id", "num"},
+				{"Order", "Title", "", "", ""},
+			},
+			values: [][]interface{}{{2, "title", "memo", "id_01", 1}},
+		},
+	} {
+		formats, err := Header(sample, c.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(formats, c.formats) {
+			t.Errorf("header %v, expected %v", formats, c.formats)
+		}
+		values, err := Marshal(sample, c.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(values, c.values) {
+			t.Errorf("marshal %v, expected %v", values, c.values)
+		}
+		actual := &SampleOrder{}
+		if err := UnmarshalValues(formats, values, actual); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(actual, sample) {
+			t.Errorf("unmarshal %+v, expected %+v", actual, sample)
+		}
+	}
+}
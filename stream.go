@@ -0,0 +1,302 @@
+package sheet
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// errTransposeStream レコードを列方向に並べるWithTransposeは1レコードずつ扱えない
+var errTransposeStream = errors.New("sheet: WithTranspose is not supported by stream")
+
+// RowWriter 1行ずつ値を書き出す先
+// rowは次の書き込みで再利用されるため保持してはいけない
+type RowWriter interface {
+	WriteRow(row []interface{}) error
+}
+
+// ValidationWriter 列に入力規則のリストを設定できるRowWriter
+type ValidationWriter interface {
+	RowWriter
+	SetValidation(column int, list []string) error
+}
+
+// StreamEncoder レコードごとに行を書き出すencoder
+type StreamEncoder struct {
+	w         RowWriter
+	opts      []Option
+	enc       *encoder
+	buf       []interface{}
+	validated bool
+}
+
+func NewStreamEncoder(w RowWriter, opts ...Option) *StreamEncoder {
+	return &StreamEncoder{
+		w:    w,
+		opts: opts,
+		enc:  newEncoder(opts...),
+	}
+}
+
+// EncodeHeader Headerの行を書き出す
+func (s *StreamEncoder) EncodeHeader(v interface{}) error {
+	if s.enc.transpose {
+		return errTransposeStream
+	}
+	formats, err := Header(v, s.opts...)
+	if err != nil {
+		return err
+	}
+	for i := range formats {
+		row := s.grow(len(formats[i]))
+		for j := range formats[i] {
+			row[j] = formats[i][j]
+		}
+		if err := s.w.WriteRow(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Encode 1レコード分の行を書き出す
+func (s *StreamEncoder) Encode(v interface{}) error {
+	return s.encode(reflect.ValueOf(v))
+}
+
+// EncodeAll Slice, Array, Chanのレコードを順番に書き出す
+func (s *StreamEncoder) EncodeAll(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if err := s.encode(rv.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Chan:
+		for {
+			x, ok := rv.Recv()
+			if !ok {
+				break
+			}
+			if err := s.encode(x); err != nil {
+				return err
+			}
+		}
+	default:
+		return errors.New("invalid encode error")
+	}
+	return nil
+}
+
+func (s *StreamEncoder) encode(rv reflect.Value) error {
+	if s.enc.transpose {
+		return errTransposeStream
+	}
+	if err := s.validate(rv); err != nil {
+		return err
+	}
+	enc := s.enc
+	enc.init()
+	defer enc.reset()
+	if err := enc.encode(rv); err != nil {
+		return err
+	}
+	width := enc.maxColumn + 1
+	buf := s.grow((enc.maxRow + 1) * width)
+	for _, cell := range enc.cells.list {
+		buf[cell.row*width+cell.column] = legacyValue(cell.value, cell.kind, cell.format)
+	}
+	for i := 0; i <= enc.maxRow; i++ {
+		if err := s.w.WriteRow(buf[i*width : (i+1)*width]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate 最初のレコードの型からenumの列の入力規則を設定する
+func (s *StreamEncoder) validate(rv reflect.Value) error {
+	if s.validated || !s.enc.validation {
+		return nil
+	}
+	s.validated = true
+	w, ok := s.w.(ValidationWriter)
+	if !ok || !rv.IsValid() {
+		return nil
+	}
+	hdr := newHeaderEncoder(s.opts...)
+	if _, err := hdr.Encode(rv.Interface()); err != nil {
+		return err
+	}
+	columns := make([]int, 0, len(hdr.validations))
+	for column := range hdr.validations {
+		columns = append(columns, column)
+	}
+	sort.Ints(columns)
+	for _, column := range columns {
+		if err := w.SetValidation(column, hdr.validations[column]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *StreamEncoder) grow(n int) []interface{} {
+	if cap(s.buf) < n {
+		s.buf = make([]interface{}, n)
+	}
+	s.buf = s.buf[:n]
+	for i := range s.buf {
+		s.buf[i] = nil
+	}
+	return s.buf
+}
+
+// CSVWriter 値を文字列にしてCSVに書き出すRowWriter
+type CSVWriter struct {
+	w      *csv.Writer
+	record []string
+}
+
+func NewCSVWriter(w io.Writer) *CSVWriter {
+	return &CSVWriter{
+		w: csv.NewWriter(w),
+	}
+}
+
+func (w *CSVWriter) WriteRow(row []interface{}) error {
+	w.record = w.record[:0]
+	for _, v := range row {
+		w.record = append(w.record, formatValue(v))
+	}
+	return w.w.Write(w.record)
+}
+
+func (w *CSVWriter) Flush() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+// RowReader 1行ずつ値を読み込む元、終端ではio.EOFを返す
+type RowReader interface {
+	Next() ([]string, error)
+}
+
+// StreamDecoder indexの列でレコードの境界を判定し、1レコードずつ読み込むdecoder
+type StreamDecoder struct {
+	r      RowReader
+	dec    *decoder
+	column int
+	rows   [][]string
+	next   []string
+}
+
+func NewStreamDecoder(formats [][]string, r RowReader, opts ...Option) *StreamDecoder {
+	return &StreamDecoder{
+		r:      r,
+		dec:    newDecoder(formats, opts...),
+		column: -1,
+	}
+}
+
+// Decode 次のレコードをvに読み込む、レコードがなければio.EOFを返す
+func (s *StreamDecoder) Decode(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("invalid decode error")
+	}
+	if s.dec.transpose {
+		return errTransposeStream
+	}
+	if s.column < 0 {
+		column, err := s.dec.indexColumn(rv.Elem())
+		if err != nil {
+			return err
+		}
+		s.column = column
+	}
+
+	for s.next == nil {
+		row, err := s.r.Next()
+		if err != nil {
+			return err
+		}
+		if !s.dec.isComment(row) {
+			s.next = row
+		}
+	}
+	s.rows = append(s.rows[:0], s.next)
+	s.next = nil
+	for {
+		row, err := s.r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if s.dec.isComment(row) {
+			continue
+		}
+		if s.column < len(row) && row[s.column] != "" {
+			s.next = row
+			break
+		}
+		s.rows = append(s.rows, row)
+	}
+	return s.dec.Decode(s.rows, v)
+}
+
+// indexColumn indexオプションのフィールドの列、indexのフィールドが無ければ先頭の列
+// ヘッダーはDecodeと同じくalias=やWithTitleMatchのtitle=とも照合する
+func (dec *decoder) indexColumn(v reflect.Value) (int, error) {
+	if err := dec.createIndex(v); err != nil {
+		return 0, err
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		_, opt, err := fieldTag(field)
+		if err != nil {
+			return 0, err
+		}
+		isIndex := opt.isIndex
+		resetOption(opt)
+		if !isIndex {
+			continue
+		}
+		if len(dec.formats) > 0 {
+			for column := range dec.formats[0] {
+				key := strings.SplitN(dec.format(0, column), ":", 2)[0]
+				if key != "" && dec.getIndex(t, key) == field.Name {
+					return column, nil
+				}
+			}
+		}
+		return 0, fmt.Errorf("sheet: index column of %s not found", field.Name)
+	}
+	return 0, nil
+}
+
+// CSVReader CSVを1行ずつ読み込むRowReader
+type CSVReader struct {
+	r *csv.Reader
+}
+
+func NewCSVReader(r io.Reader) *CSVReader {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	return &CSVReader{
+		r: cr,
+	}
+}
+
+func (r *CSVReader) Next() ([]string, error) {
+	return r.r.Read()
+}
@@ -0,0 +1,141 @@
+package sheet
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type SampleStream struct {
+	ID    string       `sheet:"id,index"`
+	Num   int          `sheet:"num"`
+	Hoges []SampleHoge `sheet:"hoges"`
+}
+
+func TestStreamEncoder(t *testing.T) {
+	ch := make(chan *SampleStream, 2)
+	ch <- &SampleStream{
+		ID:  "id_01",
+		Num: 1,
+		Hoges: []SampleHoge{
+			{Title: "title_1_1", Order: 1},
+			{Title: "title_1_2", Order: 2},
+		},
+	}
+	ch <- &SampleStream{
+		ID:  "id_02",
+		Num: 2,
+	}
+	close(ch)
+
+	buf := &bytes.Buffer{}
+	w := NewCSVWriter(buf)
+	enc := NewStreamEncoder(w)
+	if err := enc.EncodeHeader(&SampleStream{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.EncodeAll(ch); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	expected := `id,num,hoges,,
+,,_index,Title,Order
+id_01,1,1,title_1_1,1
+,,2,title_1_2,2
+id_02,2,,,
+`
+	if buf.String() != expected {
+		t.Errorf("csv %q, expected %q", buf.String(), expected)
+	}
+}
+
+func TestStreamDecoder(t *testing.T) {
+	formats := [][]string{
+		{"num", "id", "hoges", "", ""},
+		{"", "", "_index", "Title", "Order"},
+	}
+	r := NewCSVReader(strings.NewReader(`1,id_01,1,title_1_1,1
+,,2,title_1_2,2
+2,id_02,1,title_2_1,1
+3,id_03,,,
+`))
+	expected := []*SampleStream{
+		{
+			ID:  "id_01",
+			Num: 1,
+			Hoges: []SampleHoge{
+				{Title: "title_1_1", Order: 1},
+				{Title: "title_1_2", Order: 2},
+			},
+		},
+		{
+			ID:  "id_02",
+			Num: 2,
+			Hoges: []SampleHoge{
+				{Title: "title_2_1", Order: 1},
+			},
+		},
+		{
+			ID:    "id_03",
+			Num:   3,
+			Hoges: []SampleHoge{},
+		},
+	}
+	dec := NewStreamDecoder(formats, r)
+	for _, e := range expected {
+		sample := &SampleStream{}
+		if err := dec.Decode(sample); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(sample, e) {
+			t.Errorf("decode %+v, expected %+v", sample, e)
+		}
+	}
+	if err := dec.Decode(&SampleStream{}); err != io.EOF {
+		t.Errorf("decode error %v, expected %v", err, io.EOF)
+	}
+}
+
+type SampleStreamTitle struct {
+	Num int    `sheet:"num"`
+	ID  string `sheet:"id,index,title=Item ID,alias=item_id"`
+}
+
+func TestStreamDecoderIndexTitle(t *testing.T) {
+	expected := []*SampleStreamTitle{
+		{Num: 1, ID: "id_01"},
+		{ID: "id_02"},
+		{Num: 3, ID: "id_03"},
+	}
+	for _, c := range []struct {
+		formats [][]string
+		opts    []Option
+	}{
+		{formats: [][]string{{"num", "Item ID"}}, opts: []Option{WithTitleMatch()}},
+		{formats: [][]string{{"num", "item_id"}}},
+	} {
+		r := NewCSVReader(strings.NewReader("1,id_01\n,id_02\n3,id_03\n"))
+		dec := NewStreamDecoder(c.formats, r, c.opts...)
+		for _, e := range expected {
+			sample := &SampleStreamTitle{}
+			if err := dec.Decode(sample); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(sample, e) {
+				t.Errorf("decode %+v, expected %+v", sample, e)
+			}
+		}
+		if err := dec.Decode(&SampleStreamTitle{}); err != io.EOF {
+			t.Errorf("decode error %v, expected %v", err, io.EOF)
+		}
+	}
+
+	r := NewCSVReader(strings.NewReader("1,id_01\n"))
+	if err := NewStreamDecoder([][]string{{"num", "Item ID"}}, r).Decode(&SampleStreamTitle{}); err == nil {
+		t.Error("decode without index column should fail")
+	}
+}
@@ -0,0 +1,170 @@
+package sheet
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+type unionField struct {
+	key    string
+	opt    *option
+	typ    reflect.Type
+	column int
+}
+
+// unionLayout 全ての具象型のフィールドを合わせた列、先頭の列は型の名前
+type unionLayout struct {
+	fields  []unionField
+	columns map[string]int
+	width   int
+}
+
+type union struct {
+	// types 名前から具象型
+	types map[string]reflect.Type
+	// names 具象型から名前
+	names map[reflect.Type]string
+	// sorted 名前の順の具象型
+	sorted []reflect.Type
+	// unionLayout order=のみを反映した列
+	unionLayout
+	// layouts WithColumnOrderごとの列
+	layouts sync.Map
+}
+
+var (
+	unionsMu sync.RWMutex
+	unions   = map[reflect.Type]*union{}
+)
+
+// RegisterInterface インターフェースIのフィールドに入る具象型を名前と共に登録する
+// 具象型は構造体か構造体のポインタで、_typeの列に名前、続く列に全ての具象型のフィールドを出力する
+func RegisterInterface[I any](types map[string]I) {
+	u := &union{
+		types: make(map[string]reflect.Type, len(types)),
+		names: make(map[reflect.Type]string, len(types)),
+	}
+	names := make([]string, 0, len(types))
+	for name, v := range types {
+		t := reflect.TypeOf(v)
+		u.types[name] = t
+		u.names[t] = name
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		t := u.types[name]
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			panic(fmt.Sprintf("sheet: %s registered as %s is not a struct", u.types[name], name))
+		}
+		u.sorted = append(u.sorted, t)
+	}
+	l, err := newUnionLayout(u, newConfig(nil))
+	if err != nil {
+		panic(err)
+	}
+	u.unionLayout = *l
+
+	unionsMu.Lock()
+	unions[reflect.TypeOf((*I)(nil)).Elem()] = u
+	unionsMu.Unlock()
+	resetTypeInfos()
+}
+
+// newUnionLayout 名前の順に具象型のフィールドをfieldOrderの順で並べる
+// 既に並べたキーのフィールドは同じ列を使う
+func newUnionLayout(u *union, c *config) (*unionLayout, error) {
+	l := &unionLayout{
+		columns: map[string]int{},
+		width:   1,
+	}
+	for _, t := range u.sorted {
+		fields, err := typeFields(t, c)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range fields {
+			field, key := t.Field(f.index), f.key
+			if col, ok := l.columns[key]; ok {
+				// 同じキーのフィールドは同じ列を使うので、1つのセルに収まる型か同じ型に限る
+				if prev := l.field(col); prev.typ != field.Type && !(isCellType(prev.typ) && isCellType(field.Type)) {
+					return nil, fmt.Errorf("sheet: field %s of %s in %s conflicts with %s", key, field.Type, t, prev.typ)
+				}
+				continue
+			}
+			width, err := newHeaderEncoder(WithMaxDepth(1)).encodeType(field.Type, 0, 0, f.opt)
+			if err != nil {
+				return nil, err
+			}
+			l.columns[key] = l.width
+			l.fields = append(l.fields, unionField{
+				key:    key,
+				opt:    f.opt,
+				typ:    field.Type,
+				column: l.width,
+			})
+			l.width += width
+		}
+	}
+	return l, nil
+}
+
+// layout WithColumnOrderがあればその順に並べ直した列
+func (u *union) layout(c *config) (*unionLayout, error) {
+	if len(c.columnOrder) == 0 {
+		return &u.unionLayout, nil
+	}
+	if x, ok := u.layouts.Load(c.columnOrderKey); ok {
+		return x.(*unionLayout), nil
+	}
+	l, err := newUnionLayout(u, c)
+	if err != nil {
+		return nil, err
+	}
+	x, _ := u.layouts.LoadOrStore(c.columnOrderKey, l)
+	return x.(*unionLayout), nil
+}
+
+// field column列目から始まるフィールド
+func (l *unionLayout) field(column int) unionField {
+	for _, f := range l.fields {
+		if f.column == column {
+			return f
+		}
+	}
+	return unionField{}
+}
+
+// isCellType ポインタを外すと1つのセルに出力される型か否か
+func isCellType(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return isLeaf(t)
+}
+
+func lookupUnion(t reflect.Type) *union {
+	if t.Kind() != reflect.Interface {
+		return nil
+	}
+	unionsMu.RLock()
+	defer unionsMu.RUnlock()
+	return unions[t]
+}
+
+// concrete 名前に対応する具象型の構造体の型と、ポインタか否か
+func (u *union) concrete(name string) (reflect.Type, bool, error) {
+	t, ok := u.types[name]
+	if !ok {
+		return nil, false, fmt.Errorf("unknown type name %q", name)
+	}
+	if t.Kind() == reflect.Ptr {
+		return t.Elem(), true, nil
+	}
+	return t, false, nil
+}
@@ -0,0 +1,233 @@
+package sheet
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type Reward interface {
+	Amount() int
+}
+
+type ItemReward struct {
+	ItemID string `sheet:"item_id"`
+	Num    int    `sheet:"num"`
+}
+
+func (r ItemReward) Amount() int {
+	return r.Num
+}
+
+type CoinReward struct {
+	Num   int  `sheet:"num"`
+	Bonus bool `sheet:"bonus"`
+}
+
+func (r *CoinReward) Amount() int {
+	return r.Num
+}
+
+type SampleUnion struct {
+	ID      string           `sheet:"id"`
+	Reward  Reward           `sheet:"reward"`
+	Rewards []SampleUnionRow `sheet:"rewards"`
+	Memo    interface{}      `sheet:"memo"`
+}
+
+type SampleUnionRow struct {
+	Reward Reward `sheet:"reward"`
+}
+
+func init() {
+	RegisterInterface(map[string]Reward{
+		"item": ItemReward{},
+		"coin": &CoinReward{},
+	})
+}
+
+func TestInterface(t *testing.T) {
+	sample := &SampleUnion{
+		ID:     "id_01",
+		Reward: ItemReward{ItemID: "item_01", Num: 3},
+		Rewards: []SampleUnionRow{
+			{Reward: &CoinReward{Num: 100, Bonus: true}},
+			{},
+		},
+	}
+	values, err := Marshal(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]interface{}{
+		{"id_01", "item", 3, nil, "item_01", 1, "coin", 100, true, nil, nil},
+		{nil, nil, nil, nil, nil, 2, nil, nil, nil, nil, nil},
+	}
+	if !reflect.DeepEqual(values, expected) {
+		t.Errorf("marshal %v, expected %v", values, expected)
+	}
+
+	formats, err := Header(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedFormats := [][]string{
+		{"id", "reward", "", "", "", "rewards", "", "", "", "", "memo"},
+		{"", "_type", "num", "bonus", "item_id", "_index", "reward", "", "", "", ""},
+		{"", "", "", "", "", "", "_type", "num", "bonus", "item_id", ""},
+	}
+	if !reflect.DeepEqual(formats, expectedFormats) {
+		t.Errorf("header %v, expected %v", formats, expectedFormats)
+	}
+
+	actual := &SampleUnion{}
+	err = Unmarshal(formats, [][]string{{"id_01", "coin", "5", "true", ""}}, actual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(actual.Reward, &CoinReward{Num: 5, Bonus: true}) {
+		t.Errorf("unmarshal %+v", actual.Reward)
+	}
+	err = Unmarshal(formats, [][]string{{"id_01", "item", "5", "", "item_05"}}, actual)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(actual.Reward, ItemReward{ItemID: "item_05", Num: 5}) {
+		t.Errorf("unmarshal %+v", actual.Reward)
+	}
+
+	err = Unmarshal(formats, [][]string{{"id_01", "gem"}}, &SampleUnion{})
+	var cellErr *CellError
+	if !errors.As(err, &cellErr) {
+		t.Errorf("unmarshal error %v, expected CellError", err)
+	}
+
+	// 登録されていないインターフェースは1つのセルに収まる値のみ出力する
+	for _, memo := range []interface{}{1, "memo", true} {
+		values, err := Marshal(&SampleUnion{Memo: memo})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(values[0]) != len(expectedFormats[0]) || values[0][10] != memo {
+			t.Errorf("marshal memo %v, expected %v at column 10", values[0], memo)
+		}
+	}
+	if _, err := Marshal(&SampleUnion{Memo: ItemReward{}}); err == nil {
+		t.Error("marshal struct in unregistered interface should fail")
+	}
+}
+
+type SharedKey interface {
+	Shared()
+}
+
+type SharedDims struct {
+	W int `sheet:"w"`
+	H int `sheet:"h"`
+}
+
+type SharedArea struct {
+	Size SharedDims `sheet:"size"`
+	Name string     `sheet:"name"`
+}
+
+func (SharedArea) Shared() {}
+
+type SharedBuff struct {
+	Size int `sheet:"size"`
+}
+
+func (SharedBuff) Shared() {}
+
+type SharedLabel struct {
+	Name *string `sheet:"name"`
+	Size int64   `sheet:"size"`
+}
+
+func (SharedLabel) Shared() {}
+
+func TestInterfaceSharedKey(t *testing.T) {
+	register := func(types map[string]SharedKey) (err interface{}) {
+		defer func() {
+			err = recover()
+		}()
+		RegisterInterface(types)
+		return nil
+	}
+	// 1つのセルに収まる型同士は同じ列を共有できる
+	if err := register(map[string]SharedKey{"buff": SharedBuff{}, "label": SharedLabel{}}); err != nil {
+		t.Errorf("register %v", err)
+	}
+	if err := register(map[string]SharedKey{"zarea": SharedArea{}, "buff": SharedBuff{}}); err == nil {
+		t.Error("register fields of different widths with a shared key should panic")
+	}
+}
+
+type OrderedReward interface {
+	Ordered()
+}
+
+type OrderedItem struct {
+	Num    int    `sheet:"num"`
+	Bonus  bool   `sheet:"bonus"`
+	ItemID string `sheet:"item_id,order=1"`
+}
+
+func (OrderedItem) Ordered() {}
+
+type SampleOrderedUnion struct {
+	Reward OrderedReward `sheet:"reward"`
+}
+
+func init() {
+	RegisterInterface(map[string]OrderedReward{
+		"item": OrderedItem{},
+	})
+}
+
+func TestInterfaceColumnOrder(t *testing.T) {
+	sample := &SampleOrderedUnion{Reward: OrderedItem{Num: 3, Bonus: true, ItemID: "item_01"}}
+	for _, c := range []struct {
+		opts    []Option
+		formats [][]string
+		values  [][]interface{}
+	}{
+		{
+			formats: [][]string{
+				{"reward", "", "", ""},
+				{"_type", "item_id", "num", "bonus"},
+			},
+			values: [][]interface{}{{"item", "item_01", 3, true}},
+		},
+		{
+			opts: []Option{WithColumnOrder("bonus", "num")},
+			formats: [][]string{
+				{"reward", "", "", ""},
+				{"_type", "bonus", "num", "item_id"},
+			},
+			values: [][]interface{}{{"item", true, 3, "item_01"}},
+		},
+	} {
+		formats, err := Header(sample, c.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(formats, c.formats) {
+			t.Errorf("header %v, expected %v", formats, c.formats)
+		}
+		values, err := Marshal(sample, c.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(values, c.values) {
+			t.Errorf("marshal %v, expected %v", values, c.values)
+		}
+		actual := &SampleOrderedUnion{}
+		if err := UnmarshalValues(formats, values, actual); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(actual, sample) {
+			t.Errorf("unmarshal %+v, expected %+v", actual, sample)
+		}
+	}
+}
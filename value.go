@@ -0,0 +1,233 @@
+package sheet
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// assign 型付きの値をそのまま代入する、代入できない値の場合はfalseを返す
+func assign(v reflect.Value, x interface{}, opt *option) (bool, error) {
+	xv := reflect.ValueOf(x)
+	if v.Type() == typeOfTime || (opt != nil && opt.isDatetime && isIntegerKind(v.Kind())) {
+		t, ok := toTime(xv)
+		if !ok {
+			return false, nil
+		}
+		if v.Type() == typeOfTime {
+			v.Set(reflect.ValueOf(t))
+			return true, nil
+		}
+		xv = reflect.ValueOf(toUnix(t, opt))
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		if xv.Kind() != reflect.Bool {
+			return false, nil
+		}
+		v.SetBool(xv.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok, err := toInt64(xv)
+		if !ok || err != nil {
+			return ok, err
+		}
+		if v.OverflowInt(n) {
+			return false, fmt.Errorf("value %v overflows %s", x, v.Type())
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, ok, err := toUint64(xv)
+		if !ok || err != nil {
+			return ok, err
+		}
+		if v.OverflowUint(n) {
+			return false, fmt.Errorf("value %v overflows %s", x, v.Type())
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, ok := toFloat64(xv)
+		if !ok {
+			return false, nil
+		}
+		if v.OverflowFloat(f) {
+			return false, fmt.Errorf("value %v overflows %s", x, v.Type())
+		}
+		v.SetFloat(f)
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+func isIntegerKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+func toInt64(v reflect.Value) (int64, bool, error) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), true, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v.Uint() > math.MaxInt64 {
+			return 0, true, fmt.Errorf("value %d overflows int64", v.Uint())
+		}
+		return int64(v.Uint()), true, nil
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if f != math.Trunc(f) {
+			return 0, true, fmt.Errorf("value %v is not an integer", f)
+		}
+		if f < math.MinInt64 || f >= math.MaxInt64 {
+			return 0, true, fmt.Errorf("value %v overflows int64", f)
+		}
+		return int64(f), true, nil
+	}
+	return 0, false, nil
+}
+
+func toUint64(v reflect.Value) (uint64, bool, error) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Int() < 0 {
+			return 0, true, fmt.Errorf("value %d overflows uint64", v.Int())
+		}
+		return uint64(v.Int()), true, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint(), true, nil
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if f != math.Trunc(f) {
+			return 0, true, fmt.Errorf("value %v is not an integer", f)
+		}
+		if f < 0 || f >= math.MaxUint64 {
+			return 0, true, fmt.Errorf("value %v overflows uint64", f)
+		}
+		return uint64(f), true, nil
+	}
+	return 0, false, nil
+}
+
+func toFloat64(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+// toTime time.Time、またはスプレッドシートのシリアル値(1899-12-30からの日数)を時刻にする
+func toTime(v reflect.Value) (time.Time, bool) {
+	if v.Type() == typeOfTime {
+		return v.Interface().(time.Time), true
+	}
+	f, ok := toFloat64(v)
+	if !ok {
+		return time.Time{}, false
+	}
+	days := math.Floor(f)
+	sec := math.Round((f - days) * 86400)
+	return time.Date(1899, 12, 30+int(days), 0, 0, int(sec), 0, time.Local), true
+}
+
+// normalizeNumber 桁区切りのカンマと数字の前の+を取り除く
+// 3桁ごとの区切りでないカンマはそのまま残し、数値として解釈できないようにする
+func normalizeNumber(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) > 1 && s[0] == '+' && isDigit(s[1]) {
+		s = s[1:]
+	}
+	if !strings.Contains(s, ",") {
+		return s
+	}
+	sign, digits := "", s
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+	end := strings.IndexAny(digits, ".eE")
+	if end < 0 {
+		end = len(digits)
+	}
+	groups := strings.Split(digits[:end], ",")
+	for i, group := range groups {
+		if (i == 0 && (len(group) < 1 || len(group) > 3)) || (i > 0 && len(group) != 3) {
+			return s
+		}
+		for j := 0; j < len(group); j++ {
+			if !isDigit(group[j]) {
+				return s
+			}
+		}
+	}
+	return sign + strings.Join(groups, "") + digits[end:]
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func parseInt(s string, bitSize int, tolerant bool) (int64, error) {
+	if !tolerant {
+		return strconv.ParseInt(s, 10, bitSize)
+	}
+	s = normalizeNumber(s)
+	x, err := strconv.ParseInt(s, 10, bitSize)
+	if !errors.Is(err, strconv.ErrSyntax) {
+		return x, err
+	}
+	f, ferr := strconv.ParseFloat(s, 64)
+	if ferr != nil {
+		return 0, err
+	}
+	n, _, ferr := toInt64(reflect.ValueOf(f))
+	if ferr != nil {
+		return 0, ferr
+	}
+	if bitSize < 64 && (n < -1<<uint(bitSize-1) || n >= 1<<uint(bitSize-1)) {
+		return 0, &strconv.NumError{Func: "ParseInt", Num: s, Err: strconv.ErrRange}
+	}
+	return n, nil
+}
+
+func parseUint(s string, bitSize int, tolerant bool) (uint64, error) {
+	if !tolerant {
+		return strconv.ParseUint(s, 10, bitSize)
+	}
+	s = normalizeNumber(s)
+	x, err := strconv.ParseUint(s, 10, bitSize)
+	if !errors.Is(err, strconv.ErrSyntax) {
+		return x, err
+	}
+	f, ferr := strconv.ParseFloat(s, 64)
+	if ferr != nil {
+		return 0, err
+	}
+	n, _, ferr := toUint64(reflect.ValueOf(f))
+	if ferr != nil {
+		return 0, ferr
+	}
+	if bitSize < 64 && n >= 1<<uint(bitSize) {
+		return 0, &strconv.NumError{Func: "ParseUint", Num: s, Err: strconv.ErrRange}
+	}
+	return n, nil
+}
+
+// rangeError 範囲外の値をフィールドの型がわかるエラーにする
+func rangeError(err error, value string, t reflect.Type) error {
+	if errors.Is(err, strconv.ErrRange) {
+		return fmt.Errorf("value %s overflows %s", value, t)
+	}
+	return err
+}